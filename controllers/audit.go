@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDKey is the context key of the request id set by SetRequestID
+const requestIDKey = "requestId"
+
+// SetRequestID records the id of the request, which audit log entries refer
+// to
+func SetRequestID(c *gin.Context, id string) {
+	c.Set(requestIDKey, id)
+}
+
+// AuditController serves the audit log (admin only)
+type AuditController struct{}
+
+// Record godoc
+// @Summary Record audit log
+// @Description List the changes of a record, most recent first, with who made them, the request id and the field values before and after each change
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Record ID"
+// @Param page query int false "Page number" default(1)
+// @Param perPage query int false "Entries per page" default(30)
+// @Param skipTotal query bool false "Skip counting, totalItems and totalPages are -1"
+// @Success 200 {object} models.PBListResponse{items=[]models.AuditEntry}
+// @Router /api/collections/{collection}/records/{id}/audit [get]
+func (ctl AuditController) Record(c *gin.Context) {
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	paging, param, err := parsePaging(c)
+	if err != nil {
+		respondInvalidQuery(c, param, err)
+		return
+	}
+	if paging.cursor != nil {
+		respondInvalidQuery(c, "cursor", errors.New("is not supported by the audit log"))
+		return
+	}
+
+	conn := db.GetDB().Db
+	where := "WHERE collection_id = ? AND record_id = ?"
+	args := []interface{}{collection.ID, c.Param("id")}
+
+	totalItems, totalPages := -1, -1
+	if !paging.skipTotal {
+		if err := conn.QueryRow("SELECT COUNT(*) FROM _audit_log "+where, args...).Scan(&totalItems); err != nil {
+			respondError(c, err, "Failed to count audit log entries.")
+			return
+		}
+		totalPages = (totalItems + paging.perPage - 1) / paging.perPage
+	}
+
+	entries, err := queryAuditEntries(conn, where+" ORDER BY created DESC, rowid DESC LIMIT ? OFFSET ?",
+		append(args, paging.perPage, (paging.page-1)*paging.perPage)...)
+	if err != nil {
+		respondError(c, err, "Failed to fetch audit log entries.")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PBListResponse{
+		Page:       paging.page,
+		PerPage:    paging.perPage,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Items:      entries,
+	})
+}
+
+func queryAuditEntries(q querier, clauses string, args ...interface{}) ([]models.AuditEntry, error) {
+	rows, err := q.Query(`SELECT id, created, request_id, actor_type, actor_collection, actor_id,
+		collection_id, record_id, operation, changes FROM _audit_log `+clauses, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var created nullDate
+		var changes string
+		err := rows.Scan(&entry.ID, &created, &entry.RequestID, &entry.Actor.Type, &entry.Actor.Collection, &entry.Actor.ID,
+			&entry.CollectionID, &entry.RecordID, &entry.Operation, &changes)
+		if err != nil {
+			return nil, err
+		}
+		entry.Created = created.Time
+		if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+			return nil, fmt.Errorf("audit log entry %s: invalid changes: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// actor returns who makes the changes of r
+func (r *recordRequest) actor() models.AuditActor {
+	switch {
+	case r.system:
+		return models.AuditActor{Type: models.AuditActorSystem}
+	case r.admin && r.auth == nil:
+		return models.AuditActor{Type: models.AuditActorAdmin}
+	case r.admin:
+		collection, _ := r.auth["collectionName"].(string)
+		return models.AuditActor{Type: models.AuditActorAdmin, Collection: collection, ID: r.auth.ID()}
+	case r.auth != nil:
+		collection, _ := r.auth["collectionName"].(string)
+		return models.AuditActor{Type: models.AuditActorRecord, Collection: collection, ID: r.auth.ID()}
+	}
+	return models.AuditActor{Type: models.AuditActorGuest}
+}
+
+// audit adds the change of a record from before to after, either of which is
+// nil when the record did not exist, to the audit log. data holds the values
+// submitted by creates and updates, which tell whether passwords changed.
+// Updates that change no field are not logged.
+func (r *recordRequest) audit(tx *sql.Tx, operation string, collection *models.Collection, id string, before, after models.Record, data map[string]interface{}) error {
+	changes := auditChanges(collection, before, after, data)
+	if operation == models.AuditOperationUpdate && len(changes) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	actor := r.actor()
+	_, err = tx.Exec(`INSERT INTO _audit_log (id, created, request_id, actor_type, actor_collection, actor_id,
+		collection_id, record_id, operation, changes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		generateID(), time.Now().UTC(), r.requestID, actor.Type, actor.Collection, actor.ID,
+		collection.ID, id, operation, string(encoded))
+	return err
+}
+
+// auditChanges returns the fields of collection whose value differs between
+// before and after. Passwords set by data are logged as redacted values.
+func auditChanges(collection *models.Collection, before, after models.Record, data map[string]interface{}) map[string]models.AuditChange {
+	changes := map[string]models.AuditChange{}
+	if before == nil && after == nil {
+		return changes
+	}
+	for _, field := range collection.Fields {
+		if field.Type == models.FieldTypePassword {
+			if password, _ := data[field.Name].(string); password != "" {
+				change := models.AuditChange{After: models.AuditRedactedValue}
+				if before != nil {
+					change.Before = models.AuditRedactedValue
+				}
+				changes[field.Name] = change
+			}
+			continue
+		}
+
+		var change models.AuditChange
+		if before != nil {
+			change.Before = before[field.Name]
+		}
+		if after != nil {
+			change.After = after[field.Name]
+		}
+		if before != nil && after != nil && sameAuditValue(change.Before, change.After) {
+			continue
+		}
+		changes[field.Name] = change
+	}
+	return changes
+}
+
+// sameAuditValue compares two record values through their JSON encoding
+func sameAuditValue(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogRedactsPasswords(t *testing.T) {
+	conn := openTestDB(t)
+	r := newRecordsTestRouter()
+	r.GET("/api/collections/:collection/records/:id/audit", AuditController{}.Record)
+
+	userID, token := signUp(t, r, "jane")
+	path := "/api/collections/users/records/" + userID
+	status := serveJSON(t, r, http.MethodPatch, path, token, map[string]interface{}{"name": "Jane"}, nil)
+	require.Equal(t, http.StatusOK, status)
+	status = serveJSON(t, r, http.MethodPatch, path, token, map[string]interface{}{"name": "Jane"}, nil)
+	require.Equal(t, http.StatusOK, status)
+	status = serveJSON(t, r, http.MethodPatch, path, token, map[string]interface{}{
+		"password": "password456", "passwordConfirm": "password456", "oldPassword": "password123",
+	}, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	var leaked int
+	require.NoError(t, conn.QueryRow(`SELECT COUNT(*) FROM _audit_log
+		WHERE changes LIKE '%password123%' OR changes LIKE '%password456%' OR changes LIKE '%$2a$%'`).Scan(&leaked))
+	assert.Zero(t, leaked, "neither passwords nor their hashes are logged")
+
+	var log struct {
+		Items []models.AuditEntry `json:"items"`
+	}
+	status = serveJSON(t, r, http.MethodGet, path+"/audit", testAdminToken, nil, &log)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, log.Items, 3, "updates changing nothing are not logged")
+
+	changed, renamed, created := log.Items[0], log.Items[1], log.Items[2]
+	assert.Equal(t, models.AuditOperationCreate, created.Operation)
+	assert.Equal(t, models.AuditActorGuest, created.Actor.Type)
+	assert.Equal(t, models.AuditChange{Before: nil, After: models.AuditRedactedValue}, created.Changes["password"])
+
+	assert.Equal(t, models.AuditOperationUpdate, renamed.Operation)
+	assert.Equal(t, models.AuditActor{Type: models.AuditActorRecord, Collection: "users", ID: userID}, renamed.Actor)
+	assert.Equal(t, map[string]models.AuditChange{"name": {Before: "", After: "Jane"}}, renamed.Changes)
+
+	assert.Equal(t, models.AuditOperationUpdate, changed.Operation)
+	assert.Equal(t, models.AuditChange{Before: models.AuditRedactedValue, After: models.AuditRedactedValue}, changed.Changes["password"])
+}
@@ -1,10 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
-	"strconv"
 
 	"github.com/VieShare/vieshare-gin/forms"
 	"github.com/VieShare/vieshare-gin/models"
@@ -29,13 +30,27 @@ func (ctl AuthController) TokenValid(c *gin.Context) {
 
 	userID, err := authModel.FetchAuth(tokenAuth)
 	if err != nil {
-		//Token does not exists in Redis (User logged out or expired)
+		//Token does not exist in the token store (User logged out or expired)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Please login first"})
+		return
+	}
+
+	//The user must still exist
+	if _, err := userModel.One(userID); err != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Please login first"})
 		return
 	}
 
 	//To be called from GetUserID()
 	c.Set("userID", userID)
+
+	//Tokens issued before sessions existed have none
+	if tokenAuth.SessionID != "" {
+		c.Set("sessionID", tokenAuth.SessionID)
+		if err := sessionModel.Touch(tokenAuth.SessionID); err != nil {
+			log.Printf("Failed to update session %s: %v", tokenAuth.SessionID, err)
+		}
+	}
 }
 
 // Refresh Token godoc
@@ -47,7 +62,9 @@ func (ctl AuthController) TokenValid(c *gin.Context) {
 // @Produce json
 // @Param auth body forms.Token true "Auth"
 // @Success 	 200  {object}  models.AuthResponse
+// @Failure      401  {object}  models.MessageResponse
 // @Failure      406  {object}  models.MessageResponse
+// @Failure      503  {object}  models.MessageResponse
 // @Router /token/refresh [POST]
 func (ctl AuthController) Refresh(c *gin.Context) {
 	var tokenForm forms.Token
@@ -84,27 +101,40 @@ func (ctl AuthController) Refresh(c *gin.Context) {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid authorization, please login again"})
 			return
 		}
-		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["user_id"]), 10, 64)
-		if err != nil {
+		userID, ok := claims["user_id"].(string)
+		if !ok || userID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid authorization, please login again"})
+			return
+		}
+		//The user must still exist
+		if _, err := userModel.One(userID); err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid authorization, please login again"})
 			return
 		}
-		//Delete the previous Refresh Token
-		deleted, delErr := authModel.DeleteAuth(refreshUUID)
-		if delErr != nil || deleted == 0 { //if any goes wrong
+		//Tokens issued before sessions existed cannot be refreshed
+		sessionID, ok := claims["session_id"].(string)
+		if !ok || sessionID == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid authorization, please login again"})
 			return
 		}
 
-		//Create new pairs of refresh and access tokens
-		ts, createErr := authModel.CreateToken(userID)
-		if createErr != nil {
-			c.JSON(http.StatusForbidden, gin.H{"message": "Invalid authorization, please login again"})
+		//Rotate the tokens of the session, revoking it if the refresh token was already used
+		ts, err := sessionModel.Refresh(userID, sessionID, refreshUUID, sessionClient(c, ""))
+		if errors.Is(err, models.ErrRefreshTokenReused) {
+			log.Printf("Refresh token reuse for session %s of user %s from %s, session revoked", sessionID, userID, c.ClientIP())
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Refresh token reuse detected, please login again"})
 			return
 		}
-		//save the tokens metadata to redis
-		saveErr := authModel.CreateAuth(userID, ts)
-		if saveErr != nil {
+		if errors.Is(err, models.ErrSessionNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid authorization, please login again"})
+			return
+		}
+		if errors.Is(err, models.ErrTokenStore) {
+			log.Printf("Failed to store refreshed tokens: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"message": "Login is temporarily unavailable, please try again later"})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusForbidden, gin.H{"message": "Invalid authorization, please login again"})
 			return
 		}
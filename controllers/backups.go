@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/forms"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Backups are zip archives under BACKUPS_PATH holding a snapshot of the
+// database as app.db, taken with VACUUM INTO while the application keeps
+// serving requests, and the uploaded files under storage/. Restoring one is
+// done offline by replacing DB_PATH and STORAGE_PATH with its contents.
+
+const defaultBackupsPath = "./data/backups"
+
+// backupNameRegex matches the accepted backup file names
+var backupNameRegex = regexp.MustCompile(`^[a-z0-9_-]+\.zip$`)
+
+// backupMu allows a single backup at a time
+var backupMu sync.Mutex
+
+var errBackupInProgress = newAPIError(http.StatusBadRequest, "Try again later - another backup process is already running.")
+
+// BackupController creates and serves the backups (admin only)
+type BackupController struct{}
+
+// List godoc
+// @Summary List backups
+// @Description List the backup archives, most recent first
+// @Tags backups
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PBBackupFileInfo
+// @Failure 403 {object} models.PBErrorResponse
+// @Router /api/backups [get]
+func (ctl BackupController) List(c *gin.Context) {
+	entries, err := os.ReadDir(backupsPath())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		respondError(c, err, "Failed to list backups.")
+		return
+	}
+
+	backups := []models.PBBackupFileInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !backupNameRegex.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, models.PBBackupFileInfo{Key: entry.Name(), Size: info.Size(), Modified: info.ModTime().UTC()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Modified.After(backups[j].Modified) })
+
+	c.JSON(http.StatusOK, backups)
+}
+
+// Create godoc
+// @Summary Create backup
+// @Description Archive the database and the uploaded files
+// @Tags backups
+// @Accept json
+// @Security BearerAuth
+// @Param body body forms.BackupForm false "Backup name"
+// @Success 204
+// @Failure 400 {object} models.PBErrorResponse
+// @Failure 403 {object} models.PBErrorResponse
+// @Router /api/backups [post]
+func (ctl BackupController) Create(c *gin.Context) {
+	var form forms.BackupForm
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&form); err != nil {
+			respondError(c, errInvalidBody, "")
+			return
+		}
+	}
+
+	name := strings.TrimSpace(form.Name)
+	if name == "" {
+		name = "vieshare_backup_" + time.Now().UTC().Format("20060102150405") + ".zip"
+	}
+	if !backupNameRegex.MatchString(name) {
+		respondError(c, newFieldError("name", codeInvalidValue, "Must be a .zip file name with only lowercase letters, numbers, - and _."), "Failed to create backup.")
+		return
+	}
+
+	if !backupMu.TryLock() {
+		respondError(c, errBackupInProgress, "")
+		return
+	}
+	defer backupMu.Unlock()
+
+	path := filepath.Join(backupsPath(), name)
+	if _, err := os.Stat(path); err == nil {
+		respondError(c, newFieldError("name", codeInvalidValue, "Backup with the specified name already exists."), "Failed to create backup.")
+		return
+	}
+
+	if err := createBackup(path); err != nil {
+		respondError(c, err, "Failed to create backup.")
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Download godoc
+// @Summary Download backup
+// @Description Download a backup archive
+// @Tags backups
+// @Produce application/zip
+// @Security BearerAuth
+// @Param key path string true "Backup file name"
+// @Success 200 {file} file
+// @Failure 404 {object} models.PBErrorResponse
+// @Router /api/backups/{key} [get]
+func (ctl BackupController) Download(c *gin.Context) {
+	path, ok := findBackup(c)
+	if !ok {
+		return
+	}
+	c.FileAttachment(path, filepath.Base(path))
+}
+
+// Delete godoc
+// @Summary Delete backup
+// @Description Delete a backup archive
+// @Tags backups
+// @Security BearerAuth
+// @Param key path string true "Backup file name"
+// @Success 204
+// @Failure 404 {object} models.PBErrorResponse
+// @Router /api/backups/{key} [delete]
+func (ctl BackupController) Delete(c *gin.Context) {
+	path, ok := findBackup(c)
+	if !ok {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		respondError(c, err, "Failed to delete backup.")
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func backupsPath() string {
+	if path := os.Getenv("BACKUPS_PATH"); path != "" {
+		return path
+	}
+	return defaultBackupsPath
+}
+
+// findBackup returns the path of the backup named in the URL, writing a 404
+// response when there is none
+func findBackup(c *gin.Context) (string, bool) {
+	key := c.Param("key")
+	path := filepath.Join(backupsPath(), key)
+	info, err := os.Stat(path)
+	if !backupNameRegex.MatchString(key) || err != nil || info.IsDir() {
+		respondError(c, sql.ErrNoRows, "")
+		return "", false
+	}
+	return path, true
+}
+
+// createBackup writes the backup archive to path. The archive is assembled
+// under a temporary name so that a failed backup leaves nothing behind.
+func createBackup(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(dir, ".backup_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshot := filepath.Join(tmpDir, "app.db")
+	if _, err := db.GetDB().Db.Exec("VACUUM INTO ?", snapshot); err != nil {
+		return err
+	}
+
+	archive := filepath.Join(tmpDir, filepath.Base(path))
+	if err := writeBackupArchive(archive, snapshot); err != nil {
+		return err
+	}
+	return os.Rename(archive, path)
+}
+
+// writeBackupArchive zips the database snapshot and the storage directory
+func writeBackupArchive(path, snapshot string) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	archive := zip.NewWriter(file)
+	if err := addBackupFile(archive, snapshot, "app.db"); err != nil {
+		return err
+	}
+
+	root := storagePath()
+	err = filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && name == root {
+			return filepath.SkipDir
+		}
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		return addBackupFile(archive, name, filepath.ToSlash(filepath.Join("storage", rel)))
+	})
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// addBackupFile copies the file at path into archive as name
+func addBackupFile(archive *zip.Writer, path, name string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	dst, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/forms"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// batchURLRegex matches the record API paths accepted inside a batch
+var batchURLRegex = regexp.MustCompile(`^/api/collections/([^/?]+)/records(?:/([^/?]+))?/?$`)
+
+// conditionalHeaders are the headers of the batch request that are not
+// passed on to its sub-requests: they apply to the batch itself and each
+// sub-request targets a different record, e.g. with its own If-Match
+var conditionalHeaders = []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since", "If-Range"}
+
+// batchFileKeyRegex matches the multipart keys of the files uploaded for a
+// batch request, requests.<index>.<field>
+var batchFileKeyRegex = regexp.MustCompile(`^requests\.(\d+)\.(.+)$`)
+
+// batchResult is the outcome of one batch request
+type batchResult struct {
+	Status int         `json:"status"`
+	Body   interface{} `json:"body"`
+}
+
+// Batch godoc
+// @Summary Batch record operations
+// @Description Run create (POST), update (PATCH), upsert (PUT) and delete (DELETE) record requests in a single transaction. Any failure rolls back the whole batch. Send multipart/form-data with the requests in @jsonPayload to upload files as requests.<index>.<field>.
+// @Tags collections
+// @Accept json
+// @Accept multipart/form-data
+// @Produce json
+// @Param body body forms.BatchForm true "Batch requests"
+// @Success 200 {array} map[string]interface{}
+// @Router /api/batch [post]
+func (p *PocketBaseController) Batch(c *gin.Context) {
+	settings := models.GetSettings().Batch
+	if !settings.Enabled {
+		respondError(c, newAPIError(http.StatusForbidden, "Batch requests are not allowed."), "")
+		return
+	}
+
+	form, files, err := parseBatchBody(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+	if len(form.Requests) == 0 {
+		respondError(c, newFieldError("requests", codeRequired, "Cannot be blank."), "Invalid batch request.")
+		return
+	}
+	if len(form.Requests) > settings.MaxRequests {
+		respondError(c, newFieldError("requests", codeInvalidValue,
+			fmt.Sprintf("Must contain at most %d requests.", settings.MaxRequests)), "Invalid batch request.")
+		return
+	}
+
+	tx, err := db.GetDB().Db.Begin()
+	if err != nil {
+		respondError(c, err, "Failed to start transaction.")
+		return
+	}
+	defer tx.Rollback()
+
+	caller := newRecordRequest(c, nil)
+	results := make([]batchResult, 0, len(form.Requests))
+	for i, request := range form.Requests {
+		result := runBatchRequest(tx, caller, request, files[i])
+		if result.Status >= http.StatusBadRequest {
+			tx.Rollback()
+			caller.rolledBack()
+			respondError(c, &apiError{
+				Status:  http.StatusBadRequest,
+				Message: "Batch transaction failed.",
+				Data: map[string]interface{}{
+					"requests": map[string]interface{}{
+						strconv.Itoa(i): gin.H{
+							"code":     "batch_request_failed",
+							"message":  "Batch request failed.",
+							"response": result,
+						},
+					},
+				},
+			}, "")
+			return
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		caller.rolledBack()
+		respondError(c, err, "Failed to commit transaction.")
+		return
+	}
+	caller.committed()
+
+	c.JSON(http.StatusOK, results)
+}
+
+// parseBatchBody reads the body of a batch request, JSON or multipart/form-data
+// with the requests in @jsonPayload, and returns the uploaded files of each
+// request by its index
+func parseBatchBody(c *gin.Context) (forms.BatchForm, map[int]map[string][]*multipart.FileHeader, error) {
+	var form forms.BatchForm
+	if c.ContentType() != gin.MIMEMultipartPOSTForm {
+		if err := c.ShouldBindJSON(&form); err != nil {
+			return form, nil, errInvalidBody
+		}
+		return form, nil, nil
+	}
+
+	multipartForm, err := c.MultipartForm()
+	if err != nil {
+		return form, nil, errInvalidBody
+	}
+	for _, payload := range multipartForm.Value[jsonPayloadKey] {
+		if err := json.Unmarshal([]byte(payload), &form); err != nil {
+			return form, nil, errInvalidBody
+		}
+	}
+
+	files := map[int]map[string][]*multipart.FileHeader{}
+	for key, headers := range multipartForm.File {
+		m := batchFileKeyRegex.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		index, err := strconv.Atoi(m[1])
+		if err != nil || index >= len(form.Requests) {
+			continue
+		}
+		if files[index] == nil {
+			files[index] = map[string][]*multipart.FileHeader{}
+		}
+		files[index][m[2]] = headers
+	}
+	return form, files, nil
+}
+
+// runBatchRequest executes a single batch request inside tx with the
+// credentials of caller, the request carrying the batch, which collects the
+// side effects to perform after the commit or the rollback
+func runBatchRequest(tx *sql.Tx, caller *recordRequest, request forms.BatchRequest, files map[string][]*multipart.FileHeader) batchResult {
+	record, status, err := execBatchRequest(tx, caller, request, files)
+	if err != nil {
+		status, body := errorResponse(err, "Failed to process the request.")
+		return batchResult{Status: status, Body: body}
+	}
+	if record == nil {
+		return batchResult{Status: status}
+	}
+	return batchResult{Status: status, Body: record}
+}
+
+func execBatchRequest(tx *sql.Tx, caller *recordRequest, request forms.BatchRequest, files map[string][]*multipart.FileHeader) (models.Record, int, error) {
+	path, rawQuery, _ := strings.Cut(request.URL, "?")
+	m := batchURLRegex.FindStringSubmatch(path)
+	if m == nil {
+		return nil, 0, newAPIError(http.StatusBadRequest, "Unsupported batch request url.")
+	}
+
+	collection, ok := models.FindCollection(m[1])
+	if !ok {
+		return nil, 0, newAPIError(http.StatusNotFound, fmt.Sprintf("Collection '%s' not found.", m[1]))
+	}
+	id := m[2]
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, 0, newAPIError(http.StatusBadRequest, "Invalid request url query.")
+	}
+	opts, err := responseOptionsFromQuery(query)
+	if err != nil {
+		return nil, 0, newAPIError(http.StatusBadRequest, fmt.Sprintf("Invalid fields parameter: %s.", err.Error()))
+	}
+
+	data := request.Body
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	headers := caller.headers.Clone()
+	for _, key := range conditionalHeaders {
+		headers.Del(key)
+	}
+	for key, value := range request.Headers {
+		headers.Set(key, value)
+	}
+
+	method := strings.ToUpper(request.Method)
+	r := &recordRequest{
+		auth:      caller.auth,
+		admin:     caller.admin,
+		requestID: caller.requestID,
+		method:    method,
+		query:     query,
+		headers:   headers,
+		body:      data,
+		files:     files,
+	}
+	// Files stored by the request, even one that failed, are removed if the
+	// batch rolls back
+	defer func() {
+		caller.afterRollback = append(caller.afterRollback, r.afterRollback...)
+	}()
+
+	var record models.Record
+	switch {
+	case method == http.MethodPost && id == "":
+		record, err = r.create(tx, collection, data)
+	case method == http.MethodPut && id == "":
+		record, err = r.upsert(tx, collection, data)
+	case method == http.MethodPatch && id != "":
+		record, err = r.update(tx, collection, id, data)
+	case method == http.MethodDelete && id != "":
+		if err := r.delete(tx, collection, id); err != nil {
+			return nil, 0, err
+		}
+		caller.afterCommit = append(caller.afterCommit, r.afterCommit...)
+		return nil, http.StatusNoContent, nil
+	default:
+		return nil, 0, newAPIError(http.StatusBadRequest, "Unsupported batch request method.")
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	caller.afterCommit = append(caller.afterCommit, r.afterCommit...)
+
+	records, err := opts.prepare(tx, r, collection, []models.Record{record})
+	if err != nil {
+		return nil, 0, err
+	}
+	return records[0], http.StatusOK, nil
+}
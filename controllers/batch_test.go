@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngImage is the signature of a PNG file, enough for its type to be detected
+var pngImage = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestFailedBatchRemovesUploadedFiles(t *testing.T) {
+	conn := openTestDB(t)
+	r := newTestRouter()
+	r.POST("/api/batch", new(PocketBaseController).Batch)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"requests": []map[string]interface{}{
+			{"method": "POST", "url": "/api/collections/categories/records", "body": map[string]interface{}{"name": "Decks", "slug": "decks"}},
+			{"method": "POST", "url": "/api/collections/categories/records", "body": map[string]interface{}{"name": "Sneakers", "slug": "shoes"}},
+		},
+	})
+	require.NoError(t, err)
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField(jsonPayloadKey, string(payload)))
+	file, err := writer.CreateFormFile("requests.0.image", "deck.png")
+	require.NoError(t, err)
+	_, err = file.Write(pngImage)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/batch", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", testAdminToken)
+	var failed struct {
+		Data map[string]map[string]interface{} `json:"data"`
+	}
+	status := serve(t, r, req, &failed)
+	require.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, failed.Data["requests"], "1")
+
+	var count int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM categories WHERE slug = 'decks'").Scan(&count))
+	assert.Zero(t, count)
+
+	categories, ok := models.FindCollection("categories")
+	require.True(t, ok)
+	entries, err := os.ReadDir(collectionFilesDir(categories))
+	if !os.IsNotExist(err) {
+		require.NoError(t, err)
+	}
+	assert.Empty(t, entries)
+}
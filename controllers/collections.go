@@ -0,0 +1,376 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/forms"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CollectionController manages collection schemas
+type CollectionController struct{}
+
+// List godoc
+// @Summary List collections
+// @Description Get all collection schemas
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.PBListResponse
+// @Router /api/collections [get]
+func (ctl CollectionController) List(c *gin.Context) {
+	collections := models.Collections()
+	c.JSON(http.StatusOK, models.PBListResponse{
+		Page:       1,
+		PerPage:    len(collections),
+		TotalItems: len(collections),
+		TotalPages: 1,
+		Items:      collections,
+	})
+}
+
+// View godoc
+// @Summary View collection
+// @Description Get a single collection schema
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param collection path string true "Collection name"
+// @Success 200 {object} models.Collection
+// @Router /api/collections/{collection} [get]
+func (ctl CollectionController) View(c *gin.Context) {
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+// Create godoc
+// @Summary Create collection
+// @Description Create a collection and its table
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body forms.CollectionForm true "Collection"
+// @Success 200 {object} models.Collection
+// @Router /api/collections [post]
+func (ctl CollectionController) Create(c *gin.Context) {
+	var form forms.CollectionForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		respondError(c, errInvalidBody, "")
+		return
+	}
+
+	collection, err := buildCollection(form, nil)
+	if err != nil {
+		respondError(c, err, "Failed to create collection.")
+		return
+	}
+
+	if err := models.CreateCollection(collection); err != nil {
+		respondError(c, err, "Failed to create collection.")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// Update godoc
+// @Summary Update collection
+// @Description Update a collection schema and migrate its table
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param collection path string true "Collection name"
+// @Param body body forms.CollectionForm true "Collection changes"
+// @Success 200 {object} models.Collection
+// @Router /api/collections/{collection} [patch]
+func (ctl CollectionController) Update(c *gin.Context) {
+	existing, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	var form forms.CollectionForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		respondError(c, errInvalidBody, "")
+		return
+	}
+
+	collection, err := buildCollection(form, existing)
+	if err != nil {
+		respondError(c, err, "Failed to update collection.")
+		return
+	}
+
+	if err := models.UpdateCollection(existing, collection); err != nil {
+		respondError(c, err, "Failed to update collection.")
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// Delete godoc
+// @Summary Delete collection
+// @Description Delete a collection together with its table and records
+// @Tags admin
+// @Security BearerAuth
+// @Param collection path string true "Collection name"
+// @Success 204
+// @Router /api/collections/{collection} [delete]
+func (ctl CollectionController) Delete(c *gin.Context) {
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	if collection.System {
+		respondError(c, newAPIError(http.StatusBadRequest, "System collections cannot be deleted."), "")
+		return
+	}
+	for _, other := range models.Collections() {
+		if other.Name == collection.Name {
+			continue
+		}
+		for _, f := range other.Fields {
+			if f.Type == models.FieldTypeRelation && f.Relation == collection.Name {
+				respondError(c, newAPIError(http.StatusBadRequest,
+					fmt.Sprintf("The collection is referenced by %s.%s.", other.Name, f.Name)), "")
+				return
+			}
+		}
+	}
+
+	if err := models.DeleteCollection(collection); err != nil {
+		respondError(c, err, "Failed to delete collection.")
+		return
+	}
+	os.RemoveAll(collectionFilesDir(collection))
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// identifierRegex matches valid collection and field names. A leading
+// underscore is reserved for internal tables such as _collections and the
+// _superusers collection.
+var identifierRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,99}$`)
+
+// reservedFieldNames cannot be used for collection fields
+var reservedFieldNames = []string{"id", "created", "updated", "collectionid", "collectionname", "collection_id", "collection_name", "tokenkey", "token_key", "expand", "deleted", "oldpassword"}
+
+var fieldTypes = []models.FieldType{
+	models.FieldTypeText, models.FieldTypeEmail, models.FieldTypeNumber, models.FieldTypeBool,
+	models.FieldTypeDate, models.FieldTypeJSON, models.FieldTypeRelation, models.FieldTypeFile,
+	models.FieldTypePassword,
+}
+
+// buildCollection applies form to a copy of existing, or to a new collection
+// when existing is nil, and validates the result
+func buildCollection(form forms.CollectionForm, existing *models.Collection) (*models.Collection, error) {
+	var collection *models.Collection
+	if existing != nil {
+		collection = existing.Clone()
+	} else {
+		collection = &models.Collection{ID: models.NewCollectionID("pbc_"), Type: models.CollectionTypeBase, Indexes: []string{}}
+	}
+
+	if form.Name != nil {
+		collection.Name = strings.TrimSpace(*form.Name)
+	}
+	renamed := existing == nil || existing.Name != collection.Name
+	if renamed && !identifierRegex.MatchString(collection.Name) {
+		return nil, newFieldError("name", codeInvalidValue, "Must start with a letter and contain only letters, numbers and underscores.")
+	}
+	if existing == nil || !strings.EqualFold(existing.Name, collection.Name) {
+		if existing != nil && existing.System {
+			return nil, newFieldError("name", codeInvalidValue, "System collections cannot be renamed.")
+		}
+		var taken bool
+		err := db.GetDB().Db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE lower(name) = lower(?))", collection.Name).Scan(&taken)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, newFieldError("name", codeInvalidValue, "The name is already in use.")
+		}
+	}
+
+	if form.Fields != nil {
+		fields, err := buildFields(*form.Fields, collection, existing)
+		if err != nil {
+			return nil, err
+		}
+		collection.Fields = fields
+	}
+
+	if collection.IsAuth() {
+		if _, ok := passwordField(collection); !ok {
+			return nil, newFieldError("fields", codeInvalidValue, "Auth collections require a password field.")
+		}
+		if len(identityFields(collection)) == 0 {
+			return nil, newFieldError("fields", codeInvalidValue, "Auth collections require an email or username field.")
+		}
+	}
+
+	if form.Indexes != nil {
+		collection.Indexes = []string{}
+		for i, index := range *form.Indexes {
+			_, table, ok := models.ParseIndex(index)
+			if !ok || strings.Contains(strings.TrimRight(index, "; \t\n"), ";") || !(strings.EqualFold(table, collection.Name) || (existing != nil && strings.EqualFold(table, existing.Name))) {
+				return nil, newFieldError(fmt.Sprintf("indexes.%d", i), codeInvalidValue,
+					"Must be a single CREATE INDEX statement on the collection table.")
+			}
+			collection.Indexes = append(collection.Indexes, index)
+		}
+	}
+
+	if form.DefaultSort != nil {
+		collection.DefaultSort = strings.TrimSpace(*form.DefaultSort)
+	}
+	if collection.DefaultSort == "" {
+		collection.DefaultSort = "-created"
+	}
+	if _, _, err := parseSort(collection, collection.DefaultSort, false); err != nil {
+		return nil, newFieldError("defaultSort", codeInvalidValue, "Invalid sort: "+err.Error()+".")
+	}
+
+	if form.DefaultFilter != nil {
+		collection.DefaultFilter = strings.TrimSpace(*form.DefaultFilter)
+	}
+	if _, _, err := buildFilterClause(collection.DefaultFilter, (&recordRequest{}).resolver(collection)); err != nil {
+		return nil, newFieldError("defaultFilter", codeInvalidValue, "Invalid filter: "+err.Error()+".")
+	}
+
+	if form.RequireIfMatch != nil {
+		collection.RequireIfMatch = *form.RequireIfMatch
+	}
+
+	rules := []struct {
+		name string
+		form forms.NullableString
+		rule **string
+	}{
+		{"listRule", form.ListRule, &collection.ListRule},
+		{"viewRule", form.ViewRule, &collection.ViewRule},
+		{"createRule", form.CreateRule, &collection.CreateRule},
+		{"updateRule", form.UpdateRule, &collection.UpdateRule},
+		{"deleteRule", form.DeleteRule, &collection.DeleteRule},
+	}
+	for _, r := range rules {
+		if r.form.Set {
+			*r.rule = r.form.Value
+		}
+		if collection.Name == models.SuperusersCollectionName && *r.rule != nil {
+			return nil, newFieldError(r.name, codeInvalidValue, "The superusers collection is admin-only.")
+		}
+		if err := validateRule(collection, *r.rule); err != nil {
+			return nil, newFieldError(r.name, codeInvalidValue, "Invalid rule: "+err.Error()+".")
+		}
+	}
+
+	return collection, nil
+}
+
+// buildFields converts the submitted fields, keeping the id and column of
+// the existing fields they refer to
+func buildFields(submitted []forms.CollectionField, collection, existing *models.Collection) ([]models.Field, error) {
+	byID := map[string]models.Field{}
+	byName := map[string]models.Field{}
+	if existing != nil {
+		for _, f := range existing.Fields {
+			byID[f.ID] = f
+			byName[f.Name] = f
+		}
+	}
+
+	fields := make([]models.Field, 0, len(submitted))
+	columns := map[string]bool{}
+	for i, sf := range submitted {
+		key := fmt.Sprintf("fields.%d", i)
+		field := models.Field{
+			ID:        sf.ID,
+			Name:      strings.TrimSpace(sf.Name),
+			Type:      models.FieldType(sf.Type),
+			Required:  sf.Required,
+			Relation:  sf.Collection,
+			MaxSelect: sf.MaxSelect,
+			MaxSize:   sf.MaxSize,
+			MimeTypes: sf.MimeTypes,
+			Thumbs:    sf.Thumbs,
+		}
+
+		if !identifierRegex.MatchString(field.Name) {
+			return nil, newFieldError(key+".name", codeInvalidValue, "Must start with a letter and contain only letters, numbers and underscores.")
+		}
+		for _, reserved := range reservedFieldNames {
+			if strings.EqualFold(field.Name, reserved) {
+				return nil, newFieldError(key+".name", codeInvalidValue, "The name is reserved.")
+			}
+		}
+
+		previous, known := byID[field.ID]
+		if field.ID == "" {
+			previous, known = byName[field.Name]
+			field.ID = previous.ID
+		} else if !known {
+			return nil, newFieldError(key+".id", codeInvalidValue, "Does not match an existing field.")
+		}
+		if !known {
+			field.ID = models.NewCollectionID(sf.Type)
+		} else if previous.Name == field.Name {
+			field.Column = previous.Column
+		}
+
+		column := strings.ToLower(field.ColumnName())
+		if columns[column] {
+			return nil, newFieldError(key+".name", codeInvalidValue, "The name is already in use.")
+		}
+		columns[column] = true
+
+		valid := false
+		for _, t := range fieldTypes {
+			valid = valid || field.Type == t
+		}
+		if !valid {
+			return nil, newFieldError(key+".type", codeInvalidValue, "Unsupported field type.")
+		}
+
+		if field.Type == models.FieldTypeRelation {
+			_, exists := models.FindCollection(field.Relation)
+			if !exists && field.Relation != collection.Name {
+				return nil, newFieldError(key+".collection", codeInvalidValue, "Must be an existing collection.")
+			}
+		} else if field.Relation != "" {
+			return nil, newFieldError(key+".collection", codeInvalidValue, "Only relation fields reference a collection.")
+		}
+
+		if field.Type == models.FieldTypeFile {
+			if field.MaxSelect < 0 {
+				return nil, newFieldError(key+".maxSelect", codeInvalidValue, "Must be 0 or greater.")
+			}
+			if field.MaxSize < 0 {
+				return nil, newFieldError(key+".maxSize", codeInvalidValue, "Must be 0 or greater.")
+			}
+			for j, size := range field.Thumbs {
+				if _, ok := parseThumbSize(size); !ok {
+					return nil, newFieldError(fmt.Sprintf("%s.thumbs.%d", key, j), codeInvalidValue, "Must be a size such as 100x100, 100x100t, 100x100b, 100x100f, 0x100 or 100x0.")
+				}
+			}
+		} else if field.MaxSelect != 0 || field.MaxSize != 0 || len(field.MimeTypes) > 0 || len(field.Thumbs) > 0 {
+			return nil, newFieldError(key+".type", codeInvalidValue, "Only file fields have maxSelect, maxSize, mimeTypes and thumbs options.")
+		}
+
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCollectionsTestRouter returns a test router serving the collections API
+// along with the record API
+func newCollectionsTestRouter() *gin.Engine {
+	r := newRecordsTestRouter()
+	r.GET("/api/collections", CollectionController{}.List)
+	r.POST("/api/collections", CollectionController{}.Create)
+	r.GET("/api/collections/:collection", CollectionController{}.View)
+	r.PATCH("/api/collections/:collection", CollectionController{}.Update)
+	r.DELETE("/api/collections/:collection", CollectionController{}.Delete)
+	return r
+}
+
+func TestCreateCollection(t *testing.T) {
+	conn := openTestDB(t)
+	r := newCollectionsTestRouter()
+	t.Cleanup(func() { models.UnregisterCollection("brands") })
+
+	var created models.Collection
+	status := serveJSON(t, r, http.MethodPost, "/api/collections", testAdminToken, map[string]interface{}{
+		"name":     "brands",
+		"fields":   []map[string]interface{}{{"name": "name", "type": "text", "required": true}},
+		"listRule": "",
+	}, &created)
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, created.ID)
+	var exists bool
+	require.NoError(t, conn.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'brands')").Scan(&exists))
+	assert.True(t, exists)
+
+	var record map[string]interface{}
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/brands/records", testAdminToken, map[string]interface{}{"name": "Vie"}, &record)
+	require.Equal(t, http.StatusOK, status, record)
+	assert.Equal(t, created.ID, record["collectionId"])
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/brands/records", testAdminToken, map[string]interface{}{}, nil)
+	assert.Equal(t, http.StatusBadRequest, status, "required fields are validated")
+
+	var list models.PBListResponse
+	status = serveJSON(t, r, http.MethodGet, "/api/collections/brands/records", "", nil, &list)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, list.Items, 1)
+
+	for name, form := range map[string]map[string]interface{}{
+		"existing name":  {"name": "categories"},
+		"internal name":  {"name": "_brands"},
+		"reserved field": {"name": "labels", "fields": []map[string]interface{}{{"name": "created", "type": "text"}}},
+		"unknown type":   {"name": "labels", "fields": []map[string]interface{}{{"name": "name", "type": "color"}}},
+		"unknown target": {"name": "labels", "fields": []map[string]interface{}{{"name": "brand", "type": "relation", "collection": "makers"}}},
+	} {
+		status := serveJSON(t, r, http.MethodPost, "/api/collections", testAdminToken, form, nil)
+		assert.Equal(t, http.StatusBadRequest, status, name)
+	}
+}
+
+func TestRenameAndDeleteCollectionWithRelations(t *testing.T) {
+	conn := openTestDB(t)
+	r := newCollectionsTestRouter()
+	t.Cleanup(func() {
+		for _, name := range []string{"brands", "makers", "items"} {
+			models.UnregisterCollection(name)
+		}
+	})
+
+	var failed models.PBErrorResponse
+	status := serveJSON(t, r, http.MethodPost, "/api/collections", testAdminToken, map[string]interface{}{
+		"name":   "brands",
+		"fields": []map[string]interface{}{{"name": "name", "type": "text"}},
+	}, nil)
+	require.Equal(t, http.StatusOK, status)
+	status = serveJSON(t, r, http.MethodPost, "/api/collections", testAdminToken, map[string]interface{}{
+		"name":   "items",
+		"fields": []map[string]interface{}{{"name": "brand", "type": "relation", "collection": "brands"}},
+	}, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	var brand, item map[string]interface{}
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/brands/records", testAdminToken, map[string]interface{}{"name": "Vie"}, &brand)
+	require.Equal(t, http.StatusOK, status)
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/items/records", testAdminToken, map[string]interface{}{"brand": brand["id"]}, &item)
+	require.Equal(t, http.StatusOK, status, item)
+
+	status = serveJSON(t, r, http.MethodPatch, "/api/collections/brands", testAdminToken, map[string]interface{}{"name": "makers"}, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusNotFound, serveJSON(t, r, http.MethodGet, "/api/collections/brands/records", testAdminToken, nil, nil))
+
+	var items models.Collection
+	status = serveJSON(t, r, http.MethodGet, "/api/collections/items", testAdminToken, nil, &items)
+	require.Equal(t, http.StatusOK, status)
+	field, ok := items.Field("brand")
+	require.True(t, ok)
+	assert.Equal(t, "makers", field.Relation, "relations follow the renamed collection")
+
+	var expanded map[string]interface{}
+	status = serveJSON(t, r, http.MethodGet, "/api/collections/items/records/"+item["id"].(string)+"?expand=brand", testAdminToken, nil, &expanded)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "makers", expanded["expand"].(map[string]interface{})["brand"].(map[string]interface{})["collectionName"])
+
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/makers", testAdminToken, nil, &failed)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "The collection is referenced by items.brand.", failed.Message)
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/categories", testAdminToken, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, status, "built-in collections are referenced too")
+
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/items", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/makers", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+	var tables int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('items', 'makers', 'brands')").Scan(&tables))
+	assert.Zero(t, tables)
+	var stored int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM _collections WHERE name IN ('items', 'makers')").Scan(&stored))
+	assert.Zero(t, stored)
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// apiError is an error that maps directly to a PocketBase error response
+type apiError struct {
+	Status  int
+	Message string
+	Data    map[string]interface{}
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func newAPIError(status int, message string) *apiError {
+	return &apiError{Status: status, Message: message}
+}
+
+// errInvalidBody is returned when a request body cannot be decoded
+var errInvalidBody = newAPIError(http.StatusBadRequest, "Failed to load the submitted data due to invalid formatting.")
+
+// fieldErrors maps field names to validation errors. It is returned by
+// record and schema validation and rendered as the data of a 400 response.
+type fieldErrors = models.FieldErrors
+
+func newFieldError(field, code, message string) fieldErrors {
+	return models.NewFieldError(field, code, message)
+}
+
+// Validation error codes, as used by PocketBase
+const (
+	codeRequired         = "validation_required"
+	codeInvalidValue     = "validation_invalid_value"
+	codeInvalidEmail     = "validation_invalid_email"
+	codeInvalidNumber    = "validation_invalid_number"
+	codeInvalidBool      = "validation_invalid_bool"
+	codeInvalidDate      = "validation_invalid_date"
+	codeInvalidJSON      = "validation_invalid_json"
+	codeNotUnique        = "validation_not_unique"
+	codeMissingRelation  = "validation_missing_rel_records"
+	codeTooManyValues    = "validation_too_many_values"
+	codeFileSizeLimit    = "validation_file_size_limit"
+	codeInvalidMimeType  = "validation_invalid_mime_type"
+	codeLengthOutOfRange = "validation_length_out_of_range"
+	codeValuesMismatch   = "validation_values_mismatch"
+)
+
+// errorResponse returns the status and PocketBase error body for err.
+// message describes the failed operation and is used for validation and
+// unexpected errors.
+func errorResponse(err error, message string) (int, models.PBErrorResponse) {
+	var apiErr *apiError
+	var fieldErrs fieldErrors
+	var schemaErr *models.SchemaError
+
+	switch {
+	case errors.As(err, &apiErr):
+		body := models.NewPBError(apiErr.Status, apiErr.Message)
+		if apiErr.Data != nil {
+			body.Data = apiErr.Data
+		}
+		return apiErr.Status, body
+	case errors.As(err, &fieldErrs):
+		body := models.NewPBError(http.StatusBadRequest, message)
+		for field, fe := range fieldErrs {
+			body.Data[field] = fe
+		}
+		return http.StatusBadRequest, body
+	case errors.As(err, &schemaErr):
+		return http.StatusBadRequest, models.NewPBError(http.StatusBadRequest, schemaErr.Message+".")
+	case errors.Is(err, errNoFieldsToUpdate):
+		return http.StatusBadRequest, models.NewPBError(http.StatusBadRequest, "No fields to update.")
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, models.NewPBError(http.StatusNotFound, "The requested resource wasn't found.")
+	default:
+		return http.StatusInternalServerError, models.NewPBError(http.StatusInternalServerError, message)
+	}
+}
+
+// respondError writes err as a PocketBase error response. Unexpected errors
+// are attached to the context for the request logs.
+func respondError(c *gin.Context, err error, message string) {
+	status, body := errorResponse(err, message)
+	if status == http.StatusInternalServerError {
+		c.Error(err)
+	}
+	c.JSON(status, body)
+}
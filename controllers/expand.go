@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/models"
+)
+
+// maxExpandDepth limits how deep dotted expand paths may go, as in PocketBase
+const maxExpandDepth = 6
+
+// maxBackRelationRecords caps the number of records a back-relation expand
+// returns per record, the first ones created
+const maxBackRelationRecords = 1000
+
+// expandTree is the parsed form of an expand parameter: "store.user,address"
+// becomes {"store": {"user": {}}, "address": {}}
+type expandTree map[string]expandTree
+
+func parseExpand(expand string) expandTree {
+	tree := expandTree{}
+	for _, path := range strings.Split(expand, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		node := tree
+		for depth, part := range strings.Split(path, ".") {
+			if depth >= maxExpandDepth {
+				break
+			}
+			part = strings.TrimSpace(part)
+			if _, ok := node[part]; !ok {
+				node[part] = expandTree{}
+			}
+			node = node[part]
+		}
+	}
+	return tree
+}
+
+// backRelation splits a back-relation expand key such as
+// "products_via_store" into the referencing collection and its relation field
+func backRelation(name string) (*models.Collection, models.Field, bool) {
+	i := strings.LastIndex(name, "_via_")
+	if i <= 0 {
+		return nil, models.Field{}, false
+	}
+
+	collection, ok := models.FindCollection(name[:i])
+	if !ok {
+		return nil, models.Field{}, false
+	}
+	field, ok := collection.Field(name[i+len("_via_"):])
+	if !ok || field.Type != models.FieldTypeRelation {
+		return nil, models.Field{}, false
+	}
+	return collection, field, true
+}
+
+// maxIDsPerQuery keeps IN (...) lists well below SQLite's bound variable limit
+const maxIDsPerQuery = 500
+
+// relationLoader loads related records for a single request. Lookups are
+// batched per expand path with WHERE ... IN (...) queries and loaded records
+// are cached by collection and id, so expanding a page issues at most one
+// query per expand path segment (per maxIDsPerQuery ids) regardless of the
+// number of records on the page. Only records allowed by the view rule of
+// their collection are loaded.
+type relationLoader struct {
+	q       querier
+	request *recordRequest
+	cache   map[string]map[string]models.Record
+}
+
+func newRelationLoader(q querier, request *recordRequest) *relationLoader {
+	return &relationLoader{q: q, request: request, cache: map[string]map[string]models.Record{}}
+}
+
+func (l *relationLoader) cached(collection *models.Collection, id string) (models.Record, bool) {
+	record, ok := l.cache[collection.Name][id]
+	return record, ok
+}
+
+func (l *relationLoader) store(collection *models.Collection, records []models.Record) {
+	byID, ok := l.cache[collection.Name]
+	if !ok {
+		byID = map[string]models.Record{}
+		l.cache[collection.Name] = byID
+	}
+	for _, record := range records {
+		byID[record.ID()] = record
+	}
+}
+
+// load fetches the records of collection whose column value is one of
+// values, in creation order. A positive perValue keeps the first perValue
+// records of each value.
+func (l *relationLoader) load(collection *models.Collection, column string, values []string, perValue int) ([]models.Record, error) {
+	condition, ruleArgs, allowed, err := l.request.ruleCondition(collection, collection.ViewRule)
+	if err != nil || !allowed {
+		return nil, err
+	}
+	if condition != "" {
+		condition = " AND " + condition
+	}
+
+	var loaded []models.Record
+	for start := 0; start < len(values); start += maxIDsPerQuery {
+		end := start + maxIDsPerQuery
+		if end > len(values) {
+			end = len(values)
+		}
+		chunk := values[start:end]
+
+		args := make([]interface{}, len(chunk), len(chunk)+len(ruleArgs))
+		for i, v := range chunk {
+			args[i] = v
+		}
+		args = append(args, ruleArgs...)
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+		where := fmt.Sprintf("[%s].[%s] IN (%s) AND %s%s",
+			collection.Table, column, placeholders, liveCondition(collection), condition)
+		if perValue > 0 {
+			where = fmt.Sprintf("[%[1]s].[rowid] IN (SELECT [rid] FROM (SELECT [%[1]s].[rowid] AS [rid], ROW_NUMBER() OVER (PARTITION BY [%[1]s].[%[2]s] ORDER BY [%[1]s].[created], [%[1]s].[rowid]) AS [n] FROM [%[1]s] WHERE %[3]s) WHERE [n] <= %[4]d)",
+				collection.Table, column, where, perValue)
+		}
+		clauses := fmt.Sprintf("WHERE %s ORDER BY [%s].[created]", where, collection.Table)
+
+		records, err := queryRecords(l.q, collection, clauses, args...)
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, records...)
+	}
+
+	l.store(collection, loaded)
+	return loaded, nil
+}
+
+// copyRecord returns a shallow copy of a cached record without its expand
+// data, so each expand path can attach its own nested relations
+func copyRecord(record models.Record) models.Record {
+	clone := make(models.Record, len(record))
+	for k, v := range record {
+		if k != "expand" {
+			clone[k] = v
+		}
+	}
+	return clone
+}
+
+// expandRecords attaches the related records described by tree to the
+// "expand" object of each record. Unknown expand keys and relations hidden
+// from request are ignored.
+func expandRecords(q querier, request *recordRequest, collection *models.Collection, records []models.Record, tree expandTree) error {
+	return newRelationLoader(q, request).expand(collection, records, tree)
+}
+
+func (l *relationLoader) expand(collection *models.Collection, records []models.Record, tree expandTree) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	for name, subtree := range tree {
+		if field, ok := collection.Field(name); ok && field.Type == models.FieldTypeRelation {
+			target, ok := models.FindCollection(field.Relation)
+			if !ok {
+				continue
+			}
+			if err := l.expandRelation(target, records, name, subtree); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if target, field, ok := backRelation(name); ok {
+			if err := l.expandBackRelation(target, field, records, name, subtree); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *relationLoader) expandRelation(target *models.Collection, records []models.Record, name string, subtree expandTree) error {
+	var ids, missing []string
+	seen := map[string]bool{}
+	for _, record := range records {
+		id, _ := record[name].(string)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		if _, ok := l.cached(target, id); !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		if _, err := l.load(target, "id", missing, 0); err != nil {
+			return err
+		}
+	}
+
+	related := map[string]models.Record{}
+	var expanded []models.Record
+	for _, id := range ids {
+		if record, ok := l.cached(target, id); ok {
+			clone := copyRecord(record)
+			related[id] = clone
+			expanded = append(expanded, clone)
+		}
+	}
+	if err := l.expand(target, expanded, subtree); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		id, _ := record[name].(string)
+		if rel, ok := related[id]; ok {
+			record.SetExpand(name, rel)
+		}
+	}
+	return nil
+}
+
+func (l *relationLoader) expandBackRelation(target *models.Collection, field models.Field, records []models.Record, name string, subtree expandTree) error {
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID())
+	}
+
+	loaded, err := l.load(target, field.ColumnName(), ids, maxBackRelationRecords)
+	if err != nil {
+		return err
+	}
+
+	grouped := map[string][]models.Record{}
+	var expanded []models.Record
+	for _, record := range loaded {
+		parent, _ := record[field.Name].(string)
+		clone := copyRecord(record)
+		grouped[parent] = append(grouped[parent], clone)
+		expanded = append(expanded, clone)
+	}
+	if err := l.expand(target, expanded, subtree); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if rel := grouped[record.ID()]; len(rel) > 0 {
+			record.SetExpand(name, rel)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingQuerier counts the statements run through a querier
+type countingQuerier struct {
+	querier
+	queries int
+}
+
+func (q *countingQuerier) Exec(query string, args ...interface{}) (sql.Result, error) {
+	q.queries++
+	return q.querier.Exec(query, args...)
+}
+
+func (q *countingQuerier) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	q.queries++
+	return q.querier.Query(query, args...)
+}
+
+func (q *countingQuerier) QueryRow(query string, args ...interface{}) *sql.Row {
+	q.queries++
+	return q.querier.QueryRow(query, args...)
+}
+
+func TestExpandQueriesDoNotGrowWithPageSize(t *testing.T) {
+	conn := openTestDB(t)
+	products, ok := models.FindCollection("products")
+	require.True(t, ok)
+
+	_, err := conn.Exec("INSERT INTO carts (id, user) VALUES ('cart_1', 'user_sample_123')")
+	require.NoError(t, err)
+	categories := []string{"cat_vieboards", "cat_clothing", "cat_shoes", "cat_accessories"}
+	subcategories := []string{"subcat_decks", "subcat_wheels", "subcat_tshirts", "subcat_hoodies"}
+	for i := 0; i < 40; i++ {
+		id := fmt.Sprintf("prod_test_%03d", i)
+		_, err := conn.Exec(`INSERT INTO products (id, name, category, subcategory, price, store) VALUES (?, ?, ?, ?, '9.99', 'store_sample_123')`,
+			id, "Product "+id, categories[i%len(categories)], subcategories[i%len(subcategories)])
+		require.NoError(t, err)
+		_, err = conn.Exec("INSERT INTO cart_items (id, cart, product, quantity) VALUES (?, 'cart_1', ?, 1)", "item_"+id, id)
+		require.NoError(t, err)
+	}
+
+	tree := parseExpand("category,store.user,subcategory.category,cart_items_via_product.cart.user")
+	// At most one query per path segment, however many records the page
+	// has; segments whose records are already loaded run none
+	const maxQueries = 8
+
+	expandPage := func(limit int) ([]models.Record, int) {
+		records, err := queryRecords(conn, products, "WHERE [id] LIKE 'prod_test_%' ORDER BY [id] LIMIT ?", limit)
+		require.NoError(t, err)
+		require.Len(t, records, limit)
+
+		q := &countingQuerier{querier: conn}
+		require.NoError(t, expandRecords(q, &recordRequest{admin: true}, products, records, tree))
+		return records, q.queries
+	}
+
+	_, small := expandPage(2)
+	records, large := expandPage(40)
+	assert.LessOrEqual(t, small, maxQueries)
+	assert.LessOrEqual(t, large, maxQueries)
+
+	for _, record := range records {
+		expand, ok := record["expand"].(map[string]interface{})
+		require.True(t, ok, record.ID())
+		assert.Contains(t, expand, "category")
+		assert.Contains(t, expand["store"].(models.Record)["expand"], "user")
+		assert.Contains(t, expand["subcategory"].(models.Record)["expand"], "category")
+		items := expand["cart_items_via_product"].([]models.Record)
+		require.Len(t, items, 1)
+		cart := items[0]["expand"].(map[string]interface{})["cart"].(models.Record)
+		assert.Contains(t, cart["expand"], "user")
+	}
+}
+
+func TestBackRelationExpandKeepsFirstRecordsPerRecord(t *testing.T) {
+	conn := openTestDB(t)
+	stores, ok := models.FindCollection("stores")
+	require.True(t, ok)
+
+	_, err := conn.Exec("INSERT INTO stores (id, name, slug, user) VALUES ('store_large', 'Large', 'large', 'user_sample_123')")
+	require.NoError(t, err)
+	_, err = conn.Exec(`WITH RECURSIVE n(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM n WHERE i < ?)
+		INSERT INTO products (id, created, name, category, price, store)
+		SELECT printf('prod_large_%04d', i), datetime('2025-01-01', '+' || i || ' seconds'), 'Product', 'cat_vieboards', '9.99', 'store_large' FROM n`,
+		maxBackRelationRecords+4)
+	require.NoError(t, err)
+
+	records, err := queryRecords(conn, stores, "WHERE [id] IN ('store_sample_123', 'store_large') ORDER BY [id]")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.NoError(t, expandRecords(conn, &recordRequest{admin: true}, stores, records, parseExpand("products_via_store")))
+
+	large := records[0]["expand"].(map[string]interface{})["products_via_store"].([]models.Record)
+	require.Len(t, large, maxBackRelationRecords)
+	assert.Equal(t, "prod_large_0000", large[0].ID())
+	assert.Equal(t, fmt.Sprintf("prod_large_%04d", maxBackRelationRecords-1), large[len(large)-1].ID())
+	sample := records[1]["expand"].(map[string]interface{})["products_via_store"].([]models.Record)
+	assert.Len(t, sample, 3)
+}
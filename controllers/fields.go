@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/models"
+)
+
+// fieldsTree is the parsed form of the `fields` query parameter. Dotted paths
+// such as "expand.store.name" become nested nodes, "*" selects every key at
+// its level and a node may carry an excerpt modifier.
+type fieldsTree struct {
+	children map[string]*fieldsTree
+	excerpt  *excerptModifier
+}
+
+// excerptModifier implements `field:excerpt(maxLength, withEllipsis)`
+type excerptModifier struct {
+	maxLength    int
+	withEllipsis bool
+}
+
+var excerptRegex = regexp.MustCompile(`^excerpt\(\s*(\d+)\s*(?:,\s*(true|false)\s*)?\)$`)
+
+// parseFields parses a comma separated list of field paths. An empty
+// parameter returns nil, meaning no projection.
+func parseFields(raw string) (*fieldsTree, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	root := &fieldsTree{children: map[string]*fieldsTree{}}
+	for _, path := range splitFieldsParam(raw) {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		var modifier *excerptModifier
+		if i := strings.Index(path, ":"); i >= 0 {
+			m := excerptRegex.FindStringSubmatch(strings.TrimSpace(path[i+1:]))
+			if m == nil {
+				return nil, fmt.Errorf("unsupported modifier in %q", path)
+			}
+			maxLength, _ := strconv.Atoi(m[1])
+			modifier = &excerptModifier{maxLength: maxLength, withEllipsis: m[2] == "true"}
+			path = path[:i]
+		}
+
+		node := root
+		for _, part := range strings.Split(path, ".") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				return nil, fmt.Errorf("invalid field path %q", path)
+			}
+			child, ok := node.children[part]
+			if !ok {
+				child = &fieldsTree{children: map[string]*fieldsTree{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.excerpt = modifier
+	}
+
+	return root, nil
+}
+
+// splitFieldsParam splits on commas that are not inside modifier arguments
+func splitFieldsParam(raw string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, ch := range raw {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, raw[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, raw[start:])
+}
+
+// projectRecord returns a copy of record containing only the selected fields
+func (t *fieldsTree) projectRecord(record models.Record) models.Record {
+	if t == nil {
+		return record
+	}
+	return models.Record(t.projectMap(record))
+}
+
+func (t *fieldsTree) projectMap(values map[string]interface{}) map[string]interface{} {
+	projected := map[string]interface{}{}
+	_, all := t.children["*"]
+
+	for key, value := range values {
+		child, ok := t.children[key]
+		if !ok {
+			if all {
+				projected[key] = value
+			}
+			continue
+		}
+		projected[key] = child.projectValue(value)
+	}
+	return projected
+}
+
+func (t *fieldsTree) projectValue(value interface{}) interface{} {
+	if t.excerpt != nil {
+		if text, ok := value.(string); ok {
+			return t.excerpt.apply(text)
+		}
+	}
+	if len(t.children) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case models.Record:
+		return models.Record(t.projectMap(v))
+	case map[string]interface{}:
+		return t.projectMap(v)
+	case []models.Record:
+		projected := make([]models.Record, len(v))
+		for i, item := range v {
+			projected[i] = models.Record(t.projectMap(item))
+		}
+		return projected
+	case []interface{}:
+		projected := make([]interface{}, len(v))
+		for i, item := range v {
+			projected[i] = t.projectValue(item)
+		}
+		return projected
+	}
+	return value
+}
+
+var (
+	excerptBlockRegex = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	excerptTagRegex   = regexp.MustCompile(`(?s)<[^>]*>`)
+	excerptSpaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// apply strips HTML from text and truncates it to maxLength characters
+func (m *excerptModifier) apply(text string) string {
+	text = excerptBlockRegex.ReplaceAllString(text, " ")
+	text = excerptTagRegex.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	text = strings.TrimSpace(excerptSpaceRegex.ReplaceAllString(text, " "))
+
+	runes := []rune(text)
+	if len(runes) <= m.maxLength {
+		return text
+	}
+
+	excerpt := strings.TrimSpace(string(runes[:m.maxLength]))
+	if m.withEllipsis {
+		excerpt += "..."
+	}
+	return excerpt
+}
@@ -0,0 +1,460 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Files of file fields are stored on the local filesystem under
+// STORAGE_PATH/<collection id>/<record id>/<file name>, and records hold
+// their names. Uploads are written while the record is saved and removed
+// again if its transaction rolls back; files a record no longer references
+// are only deleted once the change has committed.
+
+const defaultStoragePath = "./data/storage"
+
+// jsonPayloadKey is the multipart field that may hold the non-file values of
+// a record request as a JSON object, as sent by the PocketBase SDKs
+const jsonPayloadKey = "@jsonPayload"
+
+// fileNameUnsafeRegex matches the characters replaced in uploaded file names
+var fileNameUnsafeRegex = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// FileController serves the files of file fields
+type FileController struct{}
+
+// Download godoc
+// @Summary Download file
+// @Description Serve a file of a record file field. Pass download=1 to receive it as an attachment and thumb=WxH for a thumbnail of an image, in one of the sizes allowed by the field.
+// @Tags files
+// @Produce octet-stream
+// @Param collection path string true "Collection name or id"
+// @Param recordId path string true "Record ID"
+// @Param filename path string true "File name"
+// @Param thumb query string false "Thumbnail size, e.g. 100x100, 100x100t, 100x100f or 600x0"
+// @Param download query bool false "Serve the file as an attachment"
+// @Success 200 {file} file
+// @Router /api/files/{collection}/{recordId}/{filename} [get]
+func (ctl FileController) Download(c *gin.Context) {
+	collection, ok := models.FindCollectionByID(c.Param("collection"))
+	if !ok {
+		respondError(c, sql.ErrNoRows, "")
+		return
+	}
+
+	record, err := findRecord(db.GetDB().Db, collection, c.Param("recordId"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch record.")
+		return
+	}
+
+	// Only files still referenced by the record are served
+	filename := c.Param("filename")
+	var field *models.Field
+	for i := range collection.Fields {
+		for _, name := range recordFileNames(record, collection.Fields[i]) {
+			if name == filename {
+				field = &collection.Fields[i]
+			}
+		}
+	}
+	dir, err := recordFilesDir(collection, record.ID())
+	if err != nil {
+		respondError(c, sql.ErrNoRows, "")
+		return
+	}
+	path := filepath.Join(dir, filename)
+	if info, err := os.Stat(path); field == nil || err != nil || info.IsDir() {
+		respondError(c, sql.ErrNoRows, "")
+		return
+	}
+
+	// Sizes the field does not allow, and files that are not supported
+	// images, are served in full as in PocketBase
+	if size := c.Query("thumb"); size != "" && containsString(field.Thumbs, size) {
+		thumbPath, contentType, err := thumbnail(path, size)
+		if err == nil {
+			path = thumbPath
+			c.Header("Content-Type", contentType)
+		} else if !errors.Is(err, image.ErrFormat) {
+			log.Printf("Failed to create the %s thumbnail of %s: %v", size, path, err)
+		}
+	}
+
+	// Uploaded files are served as untrusted content
+	c.Header("Content-Security-Policy", "default-src 'none'; media-src 'self'; style-src 'unsafe-inline'; sandbox")
+	c.Header("Cache-Control", "max-age=2592000, stale-while-revalidate=86400")
+	if download := c.Query("download"); download != "" && download != "0" && download != "false" {
+		c.FileAttachment(path, filename)
+		return
+	}
+	c.File(path)
+}
+
+func storagePath() string {
+	if path := os.Getenv("STORAGE_PATH"); path != "" {
+		return path
+	}
+	return defaultStoragePath
+}
+
+// collectionFilesDir returns the directory holding the files of a collection
+func collectionFilesDir(collection *models.Collection) string {
+	return filepath.Join(storagePath(), collection.ID)
+}
+
+// errUnsafeFilesDir is returned for record ids that would place the files of
+// the record outside of the directory of its collection
+var errUnsafeFilesDir = errors.New("record files directory outside of the collection directory")
+
+// recordFilesDir returns the directory holding the files of a record,
+// refusing ids that resolve outside of the collection directory
+func recordFilesDir(collection *models.Collection, recordID string) (string, error) {
+	root := collectionFilesDir(collection)
+	dir := filepath.Join(root, recordID)
+	if filepath.Dir(dir) != filepath.Clean(root) {
+		return "", errUnsafeFilesDir
+	}
+	return dir, nil
+}
+
+// parseRecordBody reads the body of a record create or update request, a
+// JSON object or multipart/form-data with uploaded files. Repeated form
+// values are collected into a list.
+func parseRecordBody(c *gin.Context) (map[string]interface{}, map[string][]*multipart.FileHeader, error) {
+	if c.ContentType() != gin.MIMEMultipartPOSTForm {
+		var data map[string]interface{}
+		if err := c.ShouldBindJSON(&data); err != nil {
+			return nil, nil, errInvalidBody
+		}
+		return data, nil, nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, nil, errInvalidBody
+	}
+
+	data := map[string]interface{}{}
+	for _, payload := range form.Value[jsonPayloadKey] {
+		if err := json.Unmarshal([]byte(payload), &data); err != nil {
+			return nil, nil, errInvalidBody
+		}
+	}
+	for key, values := range form.Value {
+		switch {
+		case key == jsonPayloadKey:
+		case len(values) == 1:
+			data[key] = values[0]
+		default:
+			list := make([]interface{}, len(values))
+			for i, value := range values {
+				list[i] = value
+			}
+			data[key] = list
+		}
+	}
+	return data, form.File, nil
+}
+
+// fileUpload is an uploaded file stored under name
+type fileUpload struct {
+	name   string
+	header *multipart.FileHeader
+}
+
+// recordFiles are the file changes of a record write
+type recordFiles struct {
+	uploads []fileUpload
+	removed []string
+}
+
+// prepareFiles resolves the file fields of a create request, when current is
+// nil, or of an update request. A value sent for a field keeps the listed
+// current files, files uploaded as the field replace the current ones and
+// files uploaded as field+ are appended; the names sent as field- are
+// removed. The resulting names are stored in data.
+func (r *recordRequest) prepareFiles(collection *models.Collection, current models.Record, data map[string]interface{}) (*recordFiles, error) {
+	changes := &recordFiles{}
+	errs := fieldErrors{}
+
+	for _, field := range collection.Fields {
+		if field.Type != models.FieldTypeFile {
+			continue
+		}
+
+		raw, set := data[field.Name]
+		removals, removing := data[field.Name+"-"]
+		delete(data, field.Name+"-")
+		delete(data, field.Name+"+")
+		uploaded := append(append([]*multipart.FileHeader{}, r.files[field.Name]...), r.files[field.Name+"+"]...)
+		if !set && !removing && len(uploaded) == 0 {
+			continue
+		}
+
+		existing := recordFileNames(current, field)
+		names := existing
+		if set {
+			kept, ok := fileNamesValue(raw)
+			if !ok {
+				errs.Add(field.Name, codeInvalidValue, "Must be a file name or a list of file names.")
+				continue
+			}
+			if unknown := subtractNames(kept, existing); len(unknown) > 0 {
+				errs.Add(field.Name, codeInvalidValue, fmt.Sprintf("Unknown file %q.", unknown[0]))
+				continue
+			}
+			names = kept
+		} else if len(r.files[field.Name]) > 0 {
+			names = nil
+		}
+		if removing {
+			list, ok := fileNamesValue(removals)
+			if !ok {
+				errs.Add(field.Name, codeInvalidValue, "Must be a file name or a list of file names.")
+				continue
+			}
+			names = subtractNames(names, list)
+		}
+
+		var uploads []fileUpload
+		for _, header := range uploaded {
+			if err := validateUpload(field, header); err != nil {
+				for name, fe := range err {
+					errs[name] = fe
+				}
+				break
+			}
+			upload := fileUpload{name: newFileName(header.Filename), header: header}
+			uploads = append(uploads, upload)
+			names = append(names, upload.name)
+		}
+		if len(uploads) < len(uploaded) {
+			continue
+		}
+
+		// Single file fields keep the most recent file
+		if !field.IsMultiple() && len(names) > 1 {
+			names = names[len(names)-1:]
+		}
+		for _, upload := range uploads {
+			if len(subtractNames([]string{upload.name}, names)) == 0 {
+				changes.uploads = append(changes.uploads, upload)
+			}
+		}
+
+		changes.removed = append(changes.removed, subtractNames(existing, names)...)
+		data[field.Name] = names
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return changes, nil
+}
+
+// storeFiles writes the uploads of changes for the record id and schedules
+// the removal of the files it no longer references
+func (r *recordRequest) storeFiles(collection *models.Collection, id string, changes *recordFiles) error {
+	dir, err := recordFilesDir(collection, id)
+	if err != nil {
+		return err
+	}
+
+	if len(changes.uploads) > 0 {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		// Run last on rollback, once the directory has been emptied
+		r.afterRollback = append(r.afterRollback, func() { os.Remove(dir) })
+	}
+	for _, upload := range changes.uploads {
+		path := filepath.Join(dir, upload.name)
+		r.afterRollback = append(r.afterRollback, func() { os.Remove(path) })
+		if err := saveUpload(upload.header, path); err != nil {
+			return err
+		}
+	}
+
+	if len(changes.removed) > 0 {
+		removed := changes.removed
+		r.afterCommit = append(r.afterCommit, func() {
+			for _, name := range removed {
+				os.Remove(filepath.Join(dir, name))
+				os.RemoveAll(thumbsDir(filepath.Join(dir, name)))
+			}
+		})
+	}
+	return nil
+}
+
+// removeRecordFiles schedules the removal of the files of a purged record
+func (r *recordRequest) removeRecordFiles(collection *models.Collection, id string) {
+	dir, err := recordFilesDir(collection, id)
+	if err != nil {
+		return
+	}
+	r.afterCommit = append(r.afterCommit, func() { os.RemoveAll(dir) })
+}
+
+// validateUpload checks an uploaded file against the size and type limits
+// of field
+func validateUpload(field models.Field, header *multipart.FileHeader) fieldErrors {
+	if header.Size > field.MaxFileSize() {
+		return newFieldError(field.Name, codeFileSizeLimit,
+			fmt.Sprintf("Failed to upload %q - the maximum allowed file size is %d bytes.", header.Filename, field.MaxFileSize()))
+	}
+	if len(field.MimeTypes) == 0 {
+		return nil
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return newFieldError(field.Name, codeInvalidValue, fmt.Sprintf("Failed to read %q.", header.Filename))
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	for _, allowed := range field.MimeTypes {
+		if strings.EqualFold(allowed, mimeType) {
+			return nil
+		}
+	}
+	return newFieldError(field.Name, codeInvalidMimeType,
+		fmt.Sprintf("Failed to upload %q - the file type %s is not allowed.", header.Filename, mimeType))
+}
+
+func saveUpload(header *multipart.FileHeader, path string) error {
+	src, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// newFileName returns the stored name of an uploaded file: its sanitized
+// original name followed by a random suffix, e.g. deck_3f9a0c81b2.webp
+func newFileName(original string) string {
+	original = filepath.Base(strings.ReplaceAll(original, "\\", "/"))
+	ext := filepath.Ext(original)
+	base := strings.TrimSuffix(original, ext)
+
+	ext = fileNameUnsafeRegex.ReplaceAllString(strings.ToLower(strings.TrimPrefix(ext, ".")), "")
+	if ext != "" {
+		ext = "." + ext
+	}
+	base = strings.Trim(fileNameUnsafeRegex.ReplaceAllString(strings.ToLower(base), "_"), "_")
+	if len(base) > 100 {
+		base = base[:100]
+	}
+	if base == "" {
+		base = "file"
+	}
+	return base + "_" + generateID()[:10] + ext
+}
+
+// recordFileNames returns the files of a file field of record, which may be
+// nil
+func recordFileNames(record models.Record, field models.Field) []string {
+	if record == nil || field.Type != models.FieldTypeFile {
+		return nil
+	}
+	names, _ := fileNamesValue(record[field.Name])
+	return names
+}
+
+// storedFileNames decodes the column of a file field, a single name or a JSON
+// list of names
+func storedFileNames(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	if strings.HasPrefix(value, "[") {
+		var names []string
+		if err := json.Unmarshal([]byte(value), &names); err == nil {
+			return names
+		}
+	}
+	return []string{value}
+}
+
+// fileNamesValue converts a submitted or record file field value into a
+// list of names
+func fileNamesValue(raw interface{}) ([]string, bool) {
+	switch v := raw.(type) {
+	case nil:
+		return []string{}, true
+	case string:
+		if v == "" {
+			return []string{}, true
+		}
+		return []string{v}, true
+	case []string:
+		return v, true
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// subtractNames returns the names not present in removed, in order
+func subtractNames(names, removed []string) []string {
+	result := []string{}
+	for _, name := range names {
+		found := false
+		for _, r := range removed {
+			found = found || r == name
+		}
+		if !found {
+			result = append(result, name)
+		}
+	}
+	return result
+}
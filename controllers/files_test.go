@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRecordRejectsPathsInID(t *testing.T) {
+	conn := openTestDB(t)
+	r := newTestRouter()
+	r.POST("/api/collections/:collection/records", new(PocketBaseController).CreateRecord)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fields := map[string]string{
+		"id": "../../escaped", "email": "jane@example.com", "username": "jane",
+		"password": "password123", "passwordConfirm": "password123",
+	}
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	file, err := writer.CreateFormFile("avatar", "avatar.png")
+	require.NoError(t, err)
+	_, err = file.Write(pngImage)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/collections/users/records", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	var failed struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	status := serve(t, r, req, &failed)
+	require.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, failed.Data, "id")
+
+	var count int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'jane@example.com'").Scan(&count))
+	assert.Zero(t, count)
+	_, err = os.Stat(filepath.Join(filepath.Dir(storagePath()), "escaped"))
+	assert.True(t, os.IsNotExist(err), "no file is written outside of the storage directory")
+}
+
+func TestRecordFilesDirStaysInCollectionDir(t *testing.T) {
+	t.Setenv("STORAGE_PATH", t.TempDir())
+	collection := &models.Collection{ID: "pbc_test"}
+
+	dir, err := recordFilesDir(collection, "abc_123")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(collectionFilesDir(collection), "abc_123"), dir)
+
+	for _, id := range []string{"", ".", "..", "../x", "a/b", "a/../../x"} {
+		_, err := recordFilesDir(collection, id)
+		assert.ErrorIs(t, err, errUnsafeFilesDir, id)
+	}
+}
@@ -0,0 +1,605 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/VieShare/vieshare-gin/models"
+)
+
+// PocketBase filter language support.
+//
+// A filter such as `(price > 20 || name ~ "deck") && store = "abc"` is parsed
+// into a small expression tree and then rendered as a parameterized SQLite
+// WHERE expression. Identifiers are never concatenated as-is: they are handed
+// to a filterFieldResolver which decides which SQL expression they map to.
+
+type filterTokenKind int
+
+const (
+	filterTokenEOF filterTokenKind = iota
+	filterTokenIdent
+	filterTokenString
+	filterTokenNumber
+	filterTokenOperator
+	filterTokenAnd
+	filterTokenOr
+	filterTokenLParen
+	filterTokenRParen
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	value string
+	pos   int
+}
+
+// filterOperators is ordered so that longer operators are matched first.
+var filterOperators = []string{
+	"?!=", "?>=", "?<=", "?!~",
+	"!=", ">=", "<=", "!~", "?=", "?>", "?<", "?~",
+	"=", ">", "<", "~",
+}
+
+// filterLexer splits a filter string into tokens.
+type filterLexer struct {
+	input []rune
+	pos   int
+}
+
+func (l *filterLexer) next() (filterToken, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.pos++
+	}
+	if l.pos >= len(l.input) {
+		return filterToken{kind: filterTokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	ch := l.input[l.pos]
+
+	switch {
+	case ch == '(':
+		l.pos++
+		return filterToken{kind: filterTokenLParen, value: "(", pos: start}, nil
+	case ch == ')':
+		l.pos++
+		return filterToken{kind: filterTokenRParen, value: ")", pos: start}, nil
+	case ch == '&' && l.peek(1) == '&':
+		l.pos += 2
+		return filterToken{kind: filterTokenAnd, value: "&&", pos: start}, nil
+	case ch == '|' && l.peek(1) == '|':
+		l.pos += 2
+		return filterToken{kind: filterTokenOr, value: "||", pos: start}, nil
+	case ch == '"' || ch == '\'':
+		return l.readString(ch)
+	case unicode.IsDigit(ch) || (ch == '-' && unicode.IsDigit(l.peek(1))):
+		return l.readNumber()
+	case isFilterIdentStart(ch):
+		for l.pos < len(l.input) && isFilterIdentPart(l.input[l.pos]) {
+			l.pos++
+		}
+		return filterToken{kind: filterTokenIdent, value: string(l.input[start:l.pos]), pos: start}, nil
+	}
+
+	for _, op := range filterOperators {
+		if strings.HasPrefix(string(l.input[l.pos:]), op) {
+			l.pos += len([]rune(op))
+			return filterToken{kind: filterTokenOperator, value: op, pos: start}, nil
+		}
+	}
+
+	return filterToken{}, fmt.Errorf("unexpected character %q at position %d", ch, start)
+}
+
+func (l *filterLexer) peek(offset int) rune {
+	if l.pos+offset < len(l.input) {
+		return l.input[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *filterLexer) readString(quote rune) (filterToken, error) {
+	start := l.pos
+	l.pos++ // opening quote
+
+	var sb strings.Builder
+	for l.pos < len(l.input) {
+		ch := l.input[l.pos]
+		if ch == '\\' && l.peek(1) == quote {
+			sb.WriteRune(quote)
+			l.pos += 2
+			continue
+		}
+		if ch == quote {
+			l.pos++
+			return filterToken{kind: filterTokenString, value: sb.String(), pos: start}, nil
+		}
+		sb.WriteRune(ch)
+		l.pos++
+	}
+
+	return filterToken{}, fmt.Errorf("unterminated string starting at position %d", start)
+}
+
+func (l *filterLexer) readNumber() (filterToken, error) {
+	start := l.pos
+	if l.input[l.pos] == '-' {
+		l.pos++
+	}
+	seenDot := false
+	for l.pos < len(l.input) {
+		ch := l.input[l.pos]
+		if ch == '.' && !seenDot {
+			seenDot = true
+		} else if !unicode.IsDigit(ch) {
+			break
+		}
+		l.pos++
+	}
+	return filterToken{kind: filterTokenNumber, value: string(l.input[start:l.pos]), pos: start}, nil
+}
+
+func isFilterIdentStart(ch rune) bool {
+	return ch == '_' || ch == '@' || unicode.IsLetter(ch)
+}
+
+func isFilterIdentPart(ch rune) bool {
+	return ch == '_' || ch == '.' || ch == ':' || ch == '@' || unicode.IsLetter(ch) || unicode.IsDigit(ch)
+}
+
+// filterNode is a node of a parsed filter expression: either a filterJoin or a
+// filterCompare.
+type filterNode interface{}
+
+// filterJoin combines two expressions with "&&" or "||".
+type filterJoin struct {
+	op    string
+	left  filterNode
+	right filterNode
+}
+
+// filterCompare is a single `operand operator operand` condition.
+type filterCompare struct {
+	left  filterToken
+	op    string
+	right filterToken
+}
+
+// filterParser is a recursive descent parser for the filter grammar:
+//
+//	expr    := and ( "||" and )*
+//	and     := primary ( "&&" primary )*
+//	primary := "(" expr ")" | operand OPERATOR operand
+//	operand := IDENT | STRING | NUMBER
+type filterParser struct {
+	lexer   *filterLexer
+	current filterToken
+}
+
+func parseFilter(filter string) (filterNode, error) {
+	p := &filterParser{lexer: &filterLexer{input: []rune(filter)}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.current.kind != filterTokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.current.value, p.current.pos)
+	}
+	return node, nil
+}
+
+func (p *filterParser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.current = tok
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.current.kind == filterTokenOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterJoin{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.current.kind == filterTokenAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterJoin{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	if p.current.kind == filterTokenLParen {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.current.kind != filterTokenRParen {
+			return nil, fmt.Errorf("missing closing parenthesis at position %d", p.current.pos)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.current.kind != filterTokenOperator {
+		return nil, fmt.Errorf("expected operator at position %d", p.current.pos)
+	}
+	op := p.current.value
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return filterCompare{left: left, op: op, right: right}, nil
+}
+
+func (p *filterParser) parseOperand() (filterToken, error) {
+	switch p.current.kind {
+	case filterTokenIdent, filterTokenString, filterTokenNumber:
+		tok := p.current
+		return tok, p.advance()
+	case filterTokenEOF:
+		return filterToken{}, fmt.Errorf("unexpected end of filter")
+	default:
+		return filterToken{}, fmt.Errorf("unexpected %q at position %d", p.current.value, p.current.pos)
+	}
+}
+
+// filterFieldResolver maps a filter identifier (e.g. "name" or "store.user")
+// to a SQL expression and its bound arguments.
+type filterFieldResolver func(name string) (string, []interface{}, error)
+
+// collectionFieldResolver resolves identifiers against a collection schema so
+// only known fields reach the generated SQL. Password fields are unknown to
+// filters and sorts. Dotted names such as
+// "store.user.name" walk relation fields through correlated sub-selects.
+// Guarded resolvers, used for the filters and sorts of non-admin requests,
+// only walk into collections everyone can list, since the sub-selects do not
+// apply the list rules of the collections they read.
+func collectionFieldResolver(collection *models.Collection, guarded bool) filterFieldResolver {
+	return newCollectionFieldResolver(collection, guarded, false)
+}
+
+// collectionFilterResolver is the collectionFieldResolver of filters and
+// rules, where date fields resolve to their value in filterDateFormat so
+// they compare with the date macros. Sorts and cursors keep the raw columns,
+// which their indexes cover.
+func collectionFilterResolver(collection *models.Collection, guarded bool) filterFieldResolver {
+	return newCollectionFieldResolver(collection, guarded, true)
+}
+
+func newCollectionFieldResolver(collection *models.Collection, guarded, formatDates bool) filterFieldResolver {
+	aliasCount := 0
+
+	return func(name string) (string, []interface{}, error) {
+		parts := strings.Split(name, ".")
+
+		current := collection
+		field, ok := current.Field(parts[0])
+		if !ok || field.Type == models.FieldTypePassword {
+			return "", nil, fmt.Errorf("unknown field %q", name)
+		}
+		expr := "[" + current.Table + "].[" + field.ColumnName() + "]"
+
+		for _, part := range parts[1:] {
+			if field.Type != models.FieldTypeRelation {
+				return "", nil, fmt.Errorf("field %q is not a relation", field.Name)
+			}
+			target, ok := models.FindCollection(field.Relation)
+			if !ok {
+				return "", nil, fmt.Errorf("unknown relation collection %q", field.Relation)
+			}
+			if guarded && !isPublicRule(target.ListRule) {
+				return "", nil, fmt.Errorf("field %q is not accessible", name)
+			}
+			field, ok = target.Field(part)
+			if !ok || field.Type == models.FieldTypePassword {
+				return "", nil, fmt.Errorf("unknown field %q", name)
+			}
+
+			aliasCount++
+			alias := fmt.Sprintf("%s_%d", target.Table, aliasCount)
+			expr = fmt.Sprintf("(SELECT [%[1]s].[%[2]s] FROM [%[3]s] [%[1]s] WHERE [%[1]s].[id] = %[4]s AND [%[1]s].[deleted] IS NULL)",
+				alias, field.ColumnName(), target.Table, expr)
+			current = target
+		}
+
+		if formatDates && field.Type == models.FieldTypeDate {
+			expr = dateSQL(expr)
+		}
+		return expr, nil, nil
+	}
+}
+
+// dateSQL returns the SQL expression formatting a datetime column with
+// filterDateFormat. Dates are stored in several layouts (the driver's, with
+// nanoseconds and a +00:00 offset, and CURRENT_TIMESTAMP's), which only
+// compare correctly with the date macros and with each other once
+// normalized. Values that are not dates become NULL.
+func dateSQL(expr string) string {
+	return "strftime('%Y-%m-%d %H:%M:%fZ', " + expr + ")"
+}
+
+// filterSQLBuilder renders a parsed filter as a SQLite expression.
+type filterSQLBuilder struct {
+	resolve filterFieldResolver
+	now     time.Time
+	args    []interface{}
+}
+
+// filterDateFormat matches the datetime layout used by PocketBase.
+const filterDateFormat = "2006-01-02 15:04:05.000Z"
+
+func (b *filterSQLBuilder) build(node filterNode) (string, error) {
+	switch n := node.(type) {
+	case filterJoin:
+		left, err := b.build(n.left)
+		if err != nil {
+			return "", err
+		}
+		right, err := b.build(n.right)
+		if err != nil {
+			return "", err
+		}
+		joiner := " AND "
+		if n.op == "||" {
+			joiner = " OR "
+		}
+		return "(" + left + joiner + right + ")", nil
+	case filterCompare:
+		return b.buildCompare(n)
+	default:
+		return "", fmt.Errorf("unsupported filter expression")
+	}
+}
+
+func (b *filterSQLBuilder) buildCompare(cmp filterCompare) (string, error) {
+	left, err := b.operand(cmp.left)
+	if err != nil {
+		return "", err
+	}
+
+	// The right side of a LIKE is wrapped in wildcards unless the client
+	// already supplied its own.
+	var right string
+	if (cmp.op == "~" || cmp.op == "!~" || cmp.op == "?~" || cmp.op == "?!~") && cmp.right.kind == filterTokenString {
+		pattern := cmp.right.value
+		if !strings.Contains(pattern, "%") {
+			pattern = "%" + escapeLikePattern(pattern) + "%"
+		}
+		b.args = append(b.args, pattern)
+		right = "?"
+	} else {
+		right, err = b.operand(cmp.right)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	op := cmp.op
+	if strings.HasPrefix(op, "?") {
+		// Any-of operators match when at least one element of a multi-valued
+		// (JSON array) field satisfies the condition. Single values are
+		// treated as one-element arrays.
+		inner, err := compareSQL("[je].[value]", op[1:], right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(%[1]s) THEN CASE WHEN json_type(%[1]s) = 'array' THEN %[1]s ELSE json_array(%[1]s) END ELSE json_array(%[1]s) END) [je] WHERE %[2]s)", left, inner), nil
+	}
+
+	return compareSQL(left, op, right)
+}
+
+func compareSQL(left, op, right string) (string, error) {
+	if right == "NULL" || left == "NULL" {
+		expr := left
+		if left == "NULL" {
+			expr = right
+		}
+		switch op {
+		case "=":
+			return fmt.Sprintf("(%[1]s IS NULL OR %[1]s = '')", expr), nil
+		case "!=":
+			return fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s != '')", expr), nil
+		}
+	}
+
+	switch op {
+	case "=":
+		return left + " IS " + right, nil
+	case "!=":
+		return left + " IS NOT " + right, nil
+	case ">", ">=", "<", "<=":
+		return left + " " + op + " " + right, nil
+	case "~":
+		return left + " LIKE " + right + " ESCAPE '\\'", nil
+	case "!~":
+		return left + " NOT LIKE " + right + " ESCAPE '\\'", nil
+	default:
+		return "", fmt.Errorf("unsupported operator %q", op)
+	}
+}
+
+func (b *filterSQLBuilder) operand(tok filterToken) (string, error) {
+	switch tok.kind {
+	case filterTokenString:
+		b.args = append(b.args, tok.value)
+		return "?", nil
+	case filterTokenNumber:
+		if i, err := strconv.ParseInt(tok.value, 10, 64); err == nil {
+			b.args = append(b.args, i)
+		} else if f, err := strconv.ParseFloat(tok.value, 64); err == nil {
+			b.args = append(b.args, f)
+		} else {
+			return "", fmt.Errorf("invalid number %q", tok.value)
+		}
+		return "?", nil
+	case filterTokenIdent:
+		switch tok.value {
+		case "null":
+			return "NULL", nil
+		case "true":
+			b.args = append(b.args, true)
+			return "?", nil
+		case "false":
+			b.args = append(b.args, false)
+			return "?", nil
+		}
+		if value, ok := b.macro(tok.value); ok {
+			b.args = append(b.args, value)
+			return "?", nil
+		}
+		expr, args, err := b.resolve(tok.value)
+		if err != nil {
+			return "", err
+		}
+		b.args = append(b.args, args...)
+		return expr, nil
+	default:
+		return "", fmt.Errorf("invalid operand %q", tok.value)
+	}
+}
+
+// macro resolves PocketBase datetime macros such as @now or @todayStart.
+func (b *filterSQLBuilder) macro(name string) (interface{}, bool) {
+	now := b.now.UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch name {
+	case "@now":
+		return now.Format(filterDateFormat), true
+	case "@yesterday":
+		return now.AddDate(0, 0, -1).Format(filterDateFormat), true
+	case "@tomorrow":
+		return now.AddDate(0, 0, 1).Format(filterDateFormat), true
+	case "@second":
+		return now.Second(), true
+	case "@minute":
+		return now.Minute(), true
+	case "@hour":
+		return now.Hour(), true
+	case "@weekday":
+		return int(now.Weekday()), true
+	case "@day":
+		return now.Day(), true
+	case "@month":
+		return int(now.Month()), true
+	case "@year":
+		return now.Year(), true
+	case "@todayStart":
+		return startOfDay.Format(filterDateFormat), true
+	case "@todayEnd":
+		return startOfDay.AddDate(0, 0, 1).Add(-time.Millisecond).Format(filterDateFormat), true
+	case "@monthStart":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).Format(filterDateFormat), true
+	case "@monthEnd":
+		return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Add(-time.Millisecond).Format(filterDateFormat), true
+	case "@yearStart":
+		return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC).Format(filterDateFormat), true
+	case "@yearEnd":
+		return time.Date(now.Year()+1, 1, 1, 0, 0, 0, 0, time.UTC).Add(-time.Millisecond).Format(filterDateFormat), true
+	}
+	return nil, false
+}
+
+func escapeLikePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
+
+// filterToSQL parses a PocketBase filter expression and returns the
+// equivalent SQLite expression together with its bound arguments.
+func filterToSQL(filter string, resolve filterFieldResolver) (string, []interface{}, error) {
+	node, err := parseFilter(filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	b := &filterSQLBuilder{resolve: resolve, now: time.Now()}
+	expr, err := b.build(node)
+	if err != nil {
+		return "", nil, err
+	}
+	return expr, b.args, nil
+}
+
+// filterIdentifiers returns the field identifiers referenced by a filter, or
+// nil when the filter cannot be parsed.
+func filterIdentifiers(filter string) []string {
+	if strings.TrimSpace(filter) == "" {
+		return nil
+	}
+	node, err := parseFilter(filter)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	var walk func(n filterNode)
+	walk = func(n filterNode) {
+		switch v := n.(type) {
+		case filterJoin:
+			walk(v.left)
+			walk(v.right)
+		case filterCompare:
+			for _, tok := range []filterToken{v.left, v.right} {
+				if tok.kind != filterTokenIdent || strings.HasPrefix(tok.value, "@") {
+					continue
+				}
+				if tok.value == "null" || tok.value == "true" || tok.value == "false" {
+					continue
+				}
+				names = append(names, tok.value)
+			}
+		}
+	}
+	walk(node)
+
+	return names
+}
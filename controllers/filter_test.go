@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filterRecords returns the ids of the records of collection matching filter
+func filterRecords(conn *sql.DB, collection *models.Collection, filter string) ([]string, error) {
+	expr, args, err := filterToSQL(filter, collectionFilterResolver(collection, false))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.Query(fmt.Sprintf("SELECT [id] FROM [%s] WHERE %s ORDER BY [id]", collection.Table, expr), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func TestFilterToSQL(t *testing.T) {
+	conn := openTestDB(t)
+	products, ok := models.FindCollection("products")
+	require.True(t, ok)
+
+	_, err := conn.Exec(`UPDATE products SET description = 'Printed "VieShare" t-shirt, 100% cotton' WHERE id = 'prod_tshirt_001'`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{`id = "prod_deck_001"`, []string{"prod_deck_001"}},
+		{`id != "prod_deck_001"`, []string{"prod_tshirt_001", "prod_wheels_001"}},
+		{`inventory > 25`, []string{"prod_tshirt_001", "prod_wheels_001"}},
+		{`inventory >= 25`, []string{"prod_deck_001", "prod_tshirt_001", "prod_wheels_001"}},
+		{`inventory < 50`, []string{"prod_deck_001"}},
+		{`rating <= 4.2`, []string{"prod_tshirt_001", "prod_wheels_001"}},
+		{`name ~ "board"`, []string{"prod_deck_001", "prod_wheels_001"}},
+		{`name !~ "board"`, []string{"prod_tshirt_001"}},
+		{`name ~ "Street%"`, []string{"prod_deck_001"}},
+		{`description ~ "Printed%cotton"`, []string{"prod_tshirt_001"}},
+		{`description ~ "100%"`, []string{}},
+		{`name ~ "T_Shirt"`, []string{}},
+		{`name ~ "T-Shirt"`, []string{"prod_tshirt_001"}},
+		{`active = true`, []string{"prod_deck_001", "prod_tshirt_001", "prod_wheels_001"}},
+		{`active = false`, []string{}},
+		{`subcategory != null`, []string{"prod_deck_001", "prod_tshirt_001", "prod_wheels_001"}},
+		{`subcategory = null`, []string{}},
+		{`inventory > 25 && rating > 4.1`, []string{"prod_wheels_001"}},
+		{`inventory < 30 || rating < 4.1`, []string{"prod_deck_001", "prod_tshirt_001"}},
+		{`(inventory < 30 || rating < 4.1) && name ~ "deck"`, []string{"prod_deck_001"}},
+		{`images ?= "deck-2.webp"`, []string{"prod_deck_001"}},
+		{`images ?~ "wheels"`, []string{"prod_wheels_001"}},
+		{`images ?!= "deck-1.webp"`, []string{"prod_deck_001", "prod_tshirt_001", "prod_wheels_001"}},
+		{`category ?= "cat_clothing"`, []string{"prod_tshirt_001"}},
+		{`category.name = "Clothing"`, []string{"prod_tshirt_001"}},
+		{`store.user.id = "user_sample_123"`, []string{"prod_deck_001", "prod_tshirt_001", "prod_wheels_001"}},
+		{`description ~ 'Printed "VieShare"'`, []string{"prod_tshirt_001"}},
+		{`description ~ "Printed \"VieShare\""`, []string{"prod_tshirt_001"}},
+		{`name = 'Street Vieboard Deck'`, []string{"prod_deck_001"}},
+		{`created <= @now`, []string{"prod_deck_001", "prod_tshirt_001", "prod_wheels_001"}},
+		{`created >= @todayStart && created <= @todayEnd`, []string{"prod_deck_001", "prod_tshirt_001", "prod_wheels_001"}},
+		{`created > @tomorrow`, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			ids, err := filterRecords(conn, products, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestFilterToSQLErrors(t *testing.T) {
+	products, ok := models.FindCollection("products")
+	require.True(t, ok)
+	resolve := collectionFilterResolver(products, false)
+
+	for _, filter := range []string{
+		`name = "unterminated`,
+		`name === "deck"`,
+		`(name = "deck"`,
+		`name = "deck" &&`,
+		`unknown = 1`,
+		`name.id = "x"`,
+		`category.password = "x"`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			_, _, err := filterToSQL(filter, resolve)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFilterTodayStartMatchesRecordsCreatedToday(t *testing.T) {
+	conn := openTestDB(t)
+	categories, ok := models.FindCollection("categories")
+	require.True(t, ok)
+
+	// Records are stored with the driver's time layout, while the macros
+	// use the PocketBase one
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	_, err := conn.Exec("UPDATE categories SET created = ? WHERE id = 'cat_shoes'", midnight)
+	require.NoError(t, err)
+	_, err = conn.Exec("UPDATE categories SET created = ? WHERE id = 'cat_clothing'", midnight.Add(-time.Nanosecond))
+	require.NoError(t, err)
+	record, err := insertRecord(conn, categories, map[string]interface{}{"name": "Decks", "slug": "decks"})
+	require.NoError(t, err)
+
+	ids, err := filterRecords(conn, categories, "created >= @todayStart")
+	require.NoError(t, err)
+	assert.Contains(t, ids, "cat_shoes")
+	assert.Contains(t, ids, record.ID())
+	assert.NotContains(t, ids, "cat_clothing")
+
+	ids, err = filterRecords(conn, categories, "created < @todayStart")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cat_clothing"}, ids)
+}
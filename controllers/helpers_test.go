@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// testAdminToken is the ADMIN_TOKEN of the test requests sent as admin
+const testAdminToken = "test-admin-token"
+
+// openTestDB initializes a new database from db/pocketbase_schema.sql, with
+// its files in a temporary directory, and loads the collections
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("DB_PATH", filepath.Join(dir, "app.db"))
+	t.Setenv("STORAGE_PATH", filepath.Join(dir, "storage"))
+	t.Setenv("ADMIN_TOKEN", testAdminToken)
+	t.Chdir("..")
+	db.Init()
+	conn := db.GetDB().Db
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, models.LoadCollections())
+	return conn
+}
+
+// newTestRouter returns a router loading the request credentials like the
+// PocketBase routes
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		LoadRequestAuth(c)
+		c.Next()
+	})
+	return r
+}
+
+// serveJSON sends a request with a JSON body, authenticated with token when
+// it is not empty, and decodes the JSON response into out
+func serveJSON(t *testing.T, r http.Handler, method, path, token string, body, out interface{}) int {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return serve(t, r, req, out)
+}
+
+// serve sends req and decodes the JSON response into out, if any
+func serve(t *testing.T, r http.Handler, req *http.Request, out interface{}) int {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if out != nil && w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), out), w.Body.String())
+	}
+	return w.Code
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeforeHooksChangeData(t *testing.T) {
+	conn := openTestDB(t)
+	r := newRecordsTestRouter()
+
+	remove := models.OnRecordBeforeCreate.Add(func(e *models.RecordEvent) error {
+		if e.Data["slug"] == nil {
+			e.Data["slug"] = "generated"
+		}
+		return nil
+	}, "categories")
+	defer remove()
+
+	var created map[string]interface{}
+	status := serveJSON(t, r, http.MethodPost, "/api/collections/categories/records", testAdminToken,
+		map[string]interface{}{"name": "Decks"}, &created)
+	require.Equal(t, http.StatusOK, status, created)
+	assert.Equal(t, "generated", created["slug"])
+
+	// Handlers only run for their collections
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/subcategories/records", testAdminToken,
+		map[string]interface{}{"name": "Decks", "category": "cat_vieboards"}, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	var count int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM subcategories WHERE slug = 'generated'").Scan(&count))
+	assert.Zero(t, count)
+}
+
+func TestBeforeHookAbortRollsBack(t *testing.T) {
+	conn := openTestDB(t)
+	r := newRecordsTestRouter()
+
+	var after []string
+	removeAfter := models.OnRecordAfterDelete.Add(func(e *models.RecordEvent) error {
+		after = append(after, e.Record.ID())
+		return nil
+	})
+	defer removeAfter()
+	remove := models.OnRecordBeforeDelete.Add(func(e *models.RecordEvent) error {
+		if e.Record.ID() == "prod_tshirt_001" {
+			return errors.New("the product has pending orders")
+		}
+		return nil
+	}, "products")
+	defer remove()
+
+	// Deleting the store trashes its products, the last one aborts
+	var failed models.PBErrorResponse
+	status := serveJSON(t, r, http.MethodDelete, "/api/collections/stores/records/store_sample_123", testAdminToken, nil, &failed)
+	require.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "the product has pending orders", failed.Message)
+
+	var trashed, logged int
+	require.NoError(t, conn.QueryRow(`SELECT (SELECT COUNT(*) FROM stores WHERE deleted IS NOT NULL)
+		+ (SELECT COUNT(*) FROM products WHERE deleted IS NOT NULL)`).Scan(&trashed))
+	assert.Zero(t, trashed, "the store and the products deleted before the abort are restored")
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM _audit_log").Scan(&logged))
+	assert.Zero(t, logged)
+	assert.Empty(t, after, "after hooks only run for committed writes")
+
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/products/records/prod_deck_001", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+	assert.Equal(t, []string{"prod_deck_001"}, after)
+}
+
+func TestBeforeHookFieldErrors(t *testing.T) {
+	openTestDB(t)
+	r := newRecordsTestRouter()
+
+	remove := models.OnRecordBeforeUpdate.Add(func(e *models.RecordEvent) error {
+		if name, _ := e.Data["name"].(string); name == "" {
+			return nil
+		}
+		return models.NewFieldError("name", codeInvalidValue, "Names are frozen.")
+	}, "categories")
+	defer remove()
+
+	var failed struct {
+		Data map[string]struct {
+			Code string `json:"code"`
+		} `json:"data"`
+	}
+	status := serveJSON(t, r, http.MethodPatch, "/api/collections/categories/records/cat_shoes", testAdminToken,
+		map[string]interface{}{"name": "Sneakers"}, &failed)
+	require.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, codeInvalidValue, failed.Data["name"].Code)
+
+	var category map[string]interface{}
+	status = serveJSON(t, r, http.MethodGet, "/api/collections/categories/records/cat_shoes", "", nil, &category)
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEqual(t, "Sneakers", category["name"])
+}
@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Request logs are queued by LogRequest and written by a single goroutine,
+// in batches, so that requests never wait for them. Entries are dropped
+// while the queue is full.
+
+const (
+	requestLogQueueSize = 1024
+	maxRequestLogBatch  = 100
+	logsPurgeInterval   = time.Hour
+)
+
+// requestLogQueue is nil until StartRequestLogs runs
+var requestLogQueue chan models.LogEntry
+
+// logColumns maps the filterable log properties to their columns
+var logColumns = map[string]string{
+	"id": "id", "created": "created", "requestId": "request_id", "method": "method", "url": "url",
+	"status": "status", "duration": "duration", "remoteIp": "remote_ip", "userAgent": "user_agent",
+	"referer": "referer", "auth": "auth", "authId": "auth_id", "error": "error",
+}
+
+// LogController serves the request logs (admin only)
+type LogController struct{}
+
+// List godoc
+// @Summary List request logs
+// @Description List the logged requests, most recent first
+// @Tags logs
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param perPage query int false "Entries per page" default(30)
+// @Param skipTotal query bool false "Skip counting, totalItems and totalPages are -1"
+// @Param filter query string false "Filter query, e.g. status >= 400"
+// @Success 200 {object} models.PBListResponse{items=[]models.LogEntry}
+// @Failure 403 {object} models.PBErrorResponse
+// @Router /api/logs [get]
+func (ctl LogController) List(c *gin.Context) {
+	paging, param, err := parsePaging(c)
+	if err != nil {
+		respondInvalidQuery(c, param, err)
+		return
+	}
+	if paging.cursor != nil {
+		respondInvalidQuery(c, "cursor", errors.New("is not supported by the request logs"))
+		return
+	}
+
+	where, args, err := buildFilterClause(c.Query("filter"), logFieldResolver)
+	if err != nil {
+		respondInvalidQuery(c, "filter", err)
+		return
+	}
+
+	conn := db.GetDB().Db
+	totalItems, totalPages := -1, -1
+	if !paging.skipTotal {
+		if err := conn.QueryRow("SELECT COUNT(*) FROM _logs "+where, args...).Scan(&totalItems); err != nil {
+			respondError(c, err, "Failed to count request logs.")
+			return
+		}
+		totalPages = (totalItems + paging.perPage - 1) / paging.perPage
+	}
+
+	entries, err := queryLogEntries(conn, where+" ORDER BY created DESC, rowid DESC LIMIT ? OFFSET ?",
+		append(args, paging.perPage, (paging.page-1)*paging.perPage)...)
+	if err != nil {
+		respondError(c, err, "Failed to fetch request logs.")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PBListResponse{
+		Page:       paging.page,
+		PerPage:    paging.perPage,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Items:      entries,
+	})
+}
+
+// View godoc
+// @Summary View request log
+// @Description Return a single logged request
+// @Tags logs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Log entry ID"
+// @Success 200 {object} models.LogEntry
+// @Failure 404 {object} models.PBErrorResponse
+// @Router /api/logs/{id} [get]
+func (ctl LogController) View(c *gin.Context) {
+	entries, err := queryLogEntries(db.GetDB().Db, "WHERE id = ?", c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch request log.")
+		return
+	}
+	if len(entries) == 0 {
+		respondError(c, sql.ErrNoRows, "")
+		return
+	}
+	c.JSON(http.StatusOK, entries[0])
+}
+
+// logFieldResolver resolves filter identifiers against the log properties
+func logFieldResolver(name string) (string, []interface{}, error) {
+	column, ok := logColumns[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown field %q", name)
+	}
+	return "[_logs].[" + column + "]", nil, nil
+}
+
+func queryLogEntries(q querier, clauses string, args ...interface{}) ([]models.LogEntry, error) {
+	rows, err := q.Query(`SELECT id, created, request_id, method, url, status, duration, remote_ip,
+		user_agent, referer, auth, auth_id, error FROM _logs `+clauses, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.LogEntry{}
+	for rows.Next() {
+		var entry models.LogEntry
+		var created nullDate
+		err := rows.Scan(&entry.ID, &created, &entry.RequestID, &entry.Method, &entry.URL, &entry.Status, &entry.Duration,
+			&entry.RemoteIP, &entry.UserAgent, &entry.Referer, &entry.Auth, &entry.AuthID, &entry.Error)
+		if err != nil {
+			return nil, err
+		}
+		entry.Created = created.Time
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// LogRequest queues the log entry of a request whose response was written,
+// unless request logs are disabled by the logs settings
+func LogRequest(c *gin.Context, started time.Time) {
+	if requestLogQueue == nil || models.GetSettings().Logs.MaxDays == 0 {
+		return
+	}
+
+	entry := models.LogEntry{
+		ID:        generateID(),
+		Created:   started.UTC(),
+		RequestID: c.GetString(requestIDKey),
+		Method:    c.Request.Method,
+		URL:       c.Request.URL.RequestURI(),
+		Status:    c.Writer.Status(),
+		Duration:  float64(time.Since(started).Microseconds()) / 1000,
+		RemoteIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Referer:   c.Request.Referer(),
+		Auth:      models.LogAuthGuest,
+		Error:     strings.Join(c.Errors.Errors(), "; "),
+	}
+	if auth := requestAuthRecord(c); auth != nil {
+		entry.Auth, _ = auth["collectionName"].(string)
+		entry.AuthID = auth.ID()
+	} else if IsAdmin(c) {
+		entry.Auth = models.LogAuthAdmin
+	}
+
+	select {
+	case requestLogQueue <- entry:
+	default:
+		log.Printf("Request log queue full, dropping %s %s", entry.Method, entry.URL)
+	}
+}
+
+// StartRequestLogs writes the queued request logs and deletes the entries
+// older than the logs settings allow, now and then every hour
+func StartRequestLogs() {
+	requestLogQueue = make(chan models.LogEntry, requestLogQueueSize)
+	go writeRequestLogs(requestLogQueue)
+
+	go func() {
+		for {
+			if days := models.GetSettings().Logs.MaxDays; days > 0 {
+				if deleted := PurgeRequestLogs(time.Now().AddDate(0, 0, -days)); deleted > 0 {
+					log.Printf("Deleted %d request logs", deleted)
+				}
+			}
+			time.Sleep(logsPurgeInterval)
+		}
+	}()
+}
+
+// writeRequestLogs inserts the entries of queue, together with those queued
+// meanwhile
+func writeRequestLogs(queue <-chan models.LogEntry) {
+	for entry := range queue {
+		entries := []models.LogEntry{entry}
+	drain:
+		for len(entries) < maxRequestLogBatch {
+			select {
+			case entry := <-queue:
+				entries = append(entries, entry)
+			default:
+				break drain
+			}
+		}
+
+		tx, err := db.GetDB().Db.Begin()
+		if err != nil {
+			log.Printf("Failed to write %d request logs: %v", len(entries), err)
+			continue
+		}
+		for _, e := range entries {
+			_, err = tx.Exec(`INSERT INTO _logs (id, created, request_id, method, url, status, duration, remote_ip,
+				user_agent, referer, auth, auth_id, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				e.ID, e.Created, e.RequestID, e.Method, e.URL, e.Status, e.Duration, e.RemoteIP,
+				e.UserAgent, e.Referer, e.Auth, e.AuthID, e.Error)
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Failed to write %d request logs: %v", len(entries), err)
+		}
+	}
+}
+
+// PurgeRequestLogs deletes the request logs created before cutoff, returning
+// how many were deleted
+func PurgeRequestLogs(cutoff time.Time) int64 {
+	result, err := db.GetDB().Db.Exec("DELETE FROM _logs WHERE created < ?", cutoff.UTC())
+	if err != nil {
+		log.Printf("Failed to delete request logs: %v", err)
+		return 0
+	}
+	deleted, _ := result.RowsAffected()
+	return deleted
+}
@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPerPage = 30
+	maxPerPage     = 100
+)
+
+// listPaging holds the validated paging parameters of a list request
+type listPaging struct {
+	page      int
+	perPage   int
+	skipTotal bool
+	cursor    *listCursor
+}
+
+// parsePaging validates page, perPage, skipTotal and cursor. Invalid values
+// are reported with the name of the offending parameter.
+func parsePaging(c *gin.Context) (listPaging, string, error) {
+	paging := listPaging{page: 1, perPage: defaultPerPage}
+
+	if raw, ok := c.GetQuery("page"); ok {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return paging, "page", errors.New("must be a positive integer")
+		}
+		paging.page = page
+	}
+
+	if raw, ok := c.GetQuery("perPage"); ok {
+		perPage, err := strconv.Atoi(raw)
+		if err != nil || perPage < 1 || perPage > maxPerPage {
+			return paging, "perPage", fmt.Errorf("must be an integer between 1 and %d", maxPerPage)
+		}
+		paging.perPage = perPage
+	}
+
+	if raw, ok := c.GetQuery("skipTotal"); ok && raw != "" {
+		skip, err := strconv.ParseBool(raw)
+		if err != nil {
+			return paging, "skipTotal", errors.New("must be a boolean")
+		}
+		paging.skipTotal = skip
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		if _, ok := c.GetQuery("page"); ok {
+			return paging, "cursor", errors.New("cannot be combined with page")
+		}
+		cursor, err := decodeCursor(raw)
+		if err != nil {
+			return paging, "cursor", err
+		}
+		paging.cursor = cursor
+	}
+
+	return paging, "", nil
+}
+
+// sortKey is a single ORDER BY term
+type sortKey struct {
+	expr string
+	desc bool
+}
+
+// parseSort converts a PocketBase sort expression such as
+// "-created,name,@random,store.name" into sort keys, rejecting any field that
+// is not part of the collection schema. Unless the sort uses @random, the
+// record id is appended as a tie-breaker so the order is stable and can be
+// resumed from a cursor. Guarded sorts follow relations like guarded filters.
+func parseSort(collection *models.Collection, sort string, guarded bool) ([]sortKey, bool, error) {
+	resolve := collectionFieldResolver(collection, guarded)
+	idExpr := "[" + collection.Table + "].[id]"
+
+	var keys []sortKey
+	random, hasID := false, false
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		key := sortKey{}
+		if strings.HasPrefix(field, "-") {
+			key.desc = true
+			field = field[1:]
+		} else if strings.HasPrefix(field, "+") {
+			field = field[1:]
+		}
+
+		switch field {
+		case "@random":
+			key.expr = "RANDOM()"
+			random = true
+		case "@rowid":
+			key.expr = "[" + collection.Table + "].[rowid]"
+		default:
+			var err error
+			key.expr, _, err = resolve(field)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		hasID = hasID || key.expr == idExpr
+		keys = append(keys, key)
+	}
+
+	if random {
+		return keys, false, nil
+	}
+	if !hasID {
+		keys = append(keys, sortKey{expr: idExpr})
+	}
+	return keys, true, nil
+}
+
+// buildSortClause renders sort keys as an ORDER BY clause
+func buildSortClause(keys []sortKey) string {
+	if len(keys) == 0 {
+		return ""
+	}
+
+	clauses := make([]string, len(keys))
+	for i, key := range keys {
+		direction := "ASC"
+		if key.desc {
+			direction = "DESC"
+		}
+		clauses[i] = key.expr + " " + direction
+	}
+	return "ORDER BY " + strings.Join(clauses, ", ")
+}
+
+// listCursor is the decoded form of the opaque cursor parameter. It holds the
+// sort key values of the last record of the previous page, each with its
+// SQLite storage class so the value is bound back with the same type.
+type listCursor struct {
+	Sort   string      `json:"s"`
+	Values [][2]string `json:"v"`
+}
+
+func encodeCursor(cursor listCursor) string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(raw string) (*listCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || len(cursor.Values) == 0 {
+		return nil, errors.New("malformed cursor")
+	}
+	return &cursor, nil
+}
+
+// cursorValue converts a stored [storage class, text] pair back to a value
+func cursorValue(pair [2]string) (interface{}, error) {
+	switch pair[0] {
+	case "null":
+		return nil, nil
+	case "integer":
+		return strconv.ParseInt(pair[1], 10, 64)
+	case "real":
+		return strconv.ParseFloat(pair[1], 64)
+	case "text":
+		return pair[1], nil
+	}
+	return nil, errors.New("malformed cursor")
+}
+
+// cursorCondition builds the keyset condition selecting the rows that sort
+// after the cursor position. NULLs sort first in ascending order in SQLite,
+// which the per-key comparisons take into account.
+func cursorCondition(keys []sortKey, sort string, cursor *listCursor) (string, []interface{}, error) {
+	if cursor.Sort != sort || len(cursor.Values) != len(keys) {
+		return "", nil, errors.New("cursor does not match the sort parameter")
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, pair := range cursor.Values {
+		value, err := cursorValue(pair)
+		if err != nil {
+			return "", nil, err
+		}
+		values[i] = value
+	}
+
+	var branches []string
+	var args []interface{}
+	for i, key := range keys {
+		var parts []string
+		var branchArgs []interface{}
+		for j := 0; j < i; j++ {
+			parts = append(parts, keys[j].expr+" IS ?")
+			branchArgs = append(branchArgs, values[j])
+		}
+
+		switch {
+		case values[i] == nil && key.desc:
+			// nothing sorts after NULL in descending order
+			continue
+		case values[i] == nil:
+			parts = append(parts, key.expr+" IS NOT NULL")
+		case key.desc:
+			parts = append(parts, "("+key.expr+" < ? OR "+key.expr+" IS NULL)")
+			branchArgs = append(branchArgs, values[i])
+		default:
+			parts = append(parts, key.expr+" > ?")
+			branchArgs = append(branchArgs, values[i])
+		}
+
+		branches = append(branches, "("+strings.Join(parts, " AND ")+")")
+		args = append(args, branchArgs...)
+	}
+
+	if len(branches) == 0 {
+		return "0", nil, nil
+	}
+	return "(" + strings.Join(branches, " OR ") + ")", args, nil
+}
+
+// nextCursor reads the sort key values of the record with the given id and
+// encodes them as the cursor for the following page. REAL values are printed
+// with 17 significant digits, which CAST rounds away, so they parse back to
+// the same float.
+func nextCursor(q querier, collection *models.Collection, keys []sortKey, sort, id string) (string, error) {
+	columns := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		columns = append(columns, "typeof("+key.expr+")",
+			fmt.Sprintf("CASE typeof(%[1]s) WHEN 'real' THEN printf('%%!.17g', %[1]s) ELSE CAST(%[1]s AS TEXT) END", key.expr))
+	}
+	query := fmt.Sprintf("SELECT %s FROM [%s] WHERE [%s].[id] = ?",
+		strings.Join(columns, ", "), collection.Table, collection.Table)
+
+	raw := make([]*string, len(columns))
+	targets := make([]interface{}, len(columns))
+	for i := range raw {
+		targets[i] = &raw[i]
+	}
+	if err := q.QueryRow(query, id).Scan(targets...); err != nil {
+		return "", err
+	}
+
+	cursor := listCursor{Sort: sort, Values: make([][2]string, len(keys))}
+	for i := range keys {
+		cursor.Values[i][0] = *raw[i*2]
+		if raw[i*2+1] != nil {
+			cursor.Values[i][1] = *raw[i*2+1]
+		}
+	}
+	return encodeCursor(cursor), nil
+}
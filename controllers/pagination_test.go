@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortUsesDateColumns(t *testing.T) {
+	products, ok := models.FindCollection("products")
+	require.True(t, ok)
+
+	keys, resumable, err := parseSort(products, "-created,category.created", false)
+	require.NoError(t, err)
+	assert.True(t, resumable)
+	require.Len(t, keys, 3)
+	assert.Equal(t, sortKey{expr: "[products].[created]", desc: true}, keys[0])
+	assert.NotContains(t, keys[1].expr, "strftime")
+}
+
+func TestCursorKeepsFloatPrecision(t *testing.T) {
+	conn := openTestDB(t)
+	r := newTestRouter()
+	r.GET("/api/collections/:collection/records", new(PocketBaseController).ListRecords)
+
+	// The ratings only differ in their last digits, which CAST AS TEXT
+	// rounds to 1.0
+	_, err := conn.Exec("UPDATE products SET rating = 0")
+	require.NoError(t, err)
+	ratings := map[string]float64{
+		"prod_deck_001":   1.0000000000000002,
+		"prod_wheels_001": 1.0000000000000004,
+		"prod_tshirt_001": 1.0000000000000007,
+	}
+	for id, rating := range ratings {
+		_, err := conn.Exec("UPDATE products SET rating = ? WHERE id = ?", rating, id)
+		require.NoError(t, err)
+	}
+
+	var ids []string
+	path := "/api/collections/products/records?perPage=1&sort=rating&filter=" + url.QueryEscape("rating > 0")
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		var list models.PBListResponse
+		query := path
+		if cursor != "" {
+			query += "&cursor=" + cursor
+		}
+		status := serveJSON(t, r, http.MethodGet, query, "", nil, &list)
+		require.Equal(t, http.StatusOK, status)
+		items, ok := list.Items.([]interface{})
+		require.True(t, ok)
+		for _, item := range items {
+			ids = append(ids, item.(map[string]interface{})["id"].(string))
+		}
+		if list.NextCursor == "" {
+			break
+		}
+		cursor = list.NextCursor
+	}
+	assert.Equal(t, []string{"prod_deck_001", "prod_wheels_001", "prod_tshirt_001"}, ids)
+}
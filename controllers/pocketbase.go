@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/VieShare/vieshare-gin/db"
 	"github.com/VieShare/vieshare-gin/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -37,61 +40,124 @@ func (p *PocketBaseController) Health(c *gin.Context) {
 // @Param collection path string true "Collection name"
 // @Param page query int false "Page number" default(1)
 // @Param perPage query int false "Records per page" default(30)
+// @Param skipTotal query bool false "Skip counting, totalItems and totalPages are -1"
+// @Param cursor query string false "Opaque cursor from a previous nextCursor"
 // @Param sort query string false "Sort fields"
 // @Param filter query string false "Filter query"
 // @Param expand query string false "Expand relations"
+// @Param fields query string false "Fields to return, e.g. id,name,description:excerpt(200,true)"
 // @Success 200 {object} models.PBListResponse
 // @Router /api/collections/{collection}/records [get]
 func (p *PocketBaseController) ListRecords(c *gin.Context) {
-	collection := c.Param("collection")
-	
-	// Parse pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	perPage, _ := strconv.Atoi(c.DefaultQuery("perPage", "30"))
-	
-	if page < 1 {
-		page = 1
-	}
-	if perPage < 1 || perPage > 100 {
-		perPage = 30
-	}
-	
-	offset := (page - 1) * perPage
-	
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	paging, param, err := parsePaging(c)
+	if err != nil {
+		respondInvalidQuery(c, param, err)
+		return
+	}
+
 	// Parse other parameters
-	sort := c.Query("sort")
-	filter := c.Query("filter")
-	expand := c.Query("expand")
-	
-	// Route to appropriate handler based on collection
-	switch collection {
-	case "users":
-		p.listUsers(c, page, perPage, offset, sort, filter, expand)
-	case "categories":
-		p.listCategories(c, page, perPage, offset, sort, filter, expand)
-	case "subcategories":
-		p.listSubcategories(c, page, perPage, offset, sort, filter, expand)
-	case "stores":
-		p.listStores(c, page, perPage, offset, sort, filter, expand)
-	case "products":
-		p.listProducts(c, page, perPage, offset, sort, filter, expand)
-	case "carts":
-		p.listCarts(c, page, perPage, offset, sort, filter, expand)
-	case "cart_items":
-		p.listCartItems(c, page, perPage, offset, sort, filter, expand)
-	case "addresses":
-		p.listAddresses(c, page, perPage, offset, sort, filter, expand)
-	case "orders":
-		p.listOrders(c, page, perPage, offset, sort, filter, expand)
-	case "customers":
-		p.listCustomers(c, page, perPage, offset, sort, filter, expand)
-	case "notifications":
-		p.listNotifications(c, page, perPage, offset, sort, filter, expand)
-	default:
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": fmt.Sprintf("Collection '%s' not found", collection),
-		})
+	sort := c.DefaultQuery("sort", collection.DefaultSort)
+	filter := listFilter(collection, c.Query("filter"))
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
+	}
+
+	dbMap := db.GetDB()
+	request := newRecordRequest(c, nil)
+
+	ruleCondition, ruleArgs, err := request.listCondition(collection)
+	if err != nil {
+		respondError(c, err, "Failed to fetch records.")
+		return
+	}
+
+	whereClause, args, err := buildFilterClause(filter, request.filterResolver(collection))
+	if err != nil {
+		respondInvalidQuery(c, "filter", err)
+		return
+	}
+	if ruleCondition != "" {
+		whereClause = andWhere(whereClause, ruleCondition)
+		args = append(args, ruleArgs...)
+	}
+	whereClause = andWhere(whereClause, liveCondition(collection))
+
+	sortKeys, resumable, err := parseSort(collection, sort, !request.admin)
+	if err != nil {
+		respondInvalidQuery(c, "sort", err)
+		return
+	}
+
+	// Get total count, unless skipped or paging by cursor
+	totalItems, totalPages := -1, -1
+	if !paging.skipTotal && paging.cursor == nil {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM [%s] %s", collection.Table, whereClause)
+		if err := dbMap.Db.QueryRow(countQuery, args...).Scan(&totalItems); err != nil {
+			respondError(c, err, "Failed to count records.")
+			return
+		}
+
+		totalPages = totalItems / paging.perPage
+		if totalItems%paging.perPage > 0 {
+			totalPages++
+		}
+	}
+
+	offset := (paging.page - 1) * paging.perPage
+	if paging.cursor != nil {
+		if !resumable {
+			respondInvalidQuery(c, "cursor", errors.New("cannot be used with @random sort"))
+			return
+		}
+		condition, cursorArgs, err := cursorCondition(sortKeys, sort, paging.cursor)
+		if err != nil {
+			respondInvalidQuery(c, "cursor", err)
+			return
+		}
+		whereClause = andWhere(whereClause, condition)
+		args = append(args, cursorArgs...)
+		offset = 0
 	}
+
+	records, err := queryRecords(dbMap.Db, collection, whereClause+" "+buildSortClause(sortKeys)+" LIMIT ? OFFSET ?",
+		append(args, paging.perPage, offset)...)
+	if err != nil {
+		respondError(c, err, "Failed to fetch records.")
+		return
+	}
+
+	// A full page may be followed by more records
+	var next string
+	if resumable && len(records) == paging.perPage {
+		next, err = nextCursor(dbMap.Db, collection, sortKeys, sort, records[len(records)-1].ID())
+		if err != nil {
+			respondError(c, err, "Failed to fetch records.")
+			return
+		}
+	}
+
+	// Handle expand relations and field projection
+	records, err = opts.prepare(dbMap.Db, request, collection, records)
+	if err != nil {
+		respondError(c, err, "Failed to expand records.")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PBListResponse{
+		Page:       paging.page,
+		PerPage:    paging.perPage,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Items:      records,
+		NextCursor: next,
+	})
 }
 
 // GetRecord godoc
@@ -102,148 +168,130 @@ func (p *PocketBaseController) ListRecords(c *gin.Context) {
 // @Param collection path string true "Collection name"
 // @Param id path string true "Record ID"
 // @Param expand query string false "Expand relations"
+// @Param fields query string false "Fields to return, e.g. id,name,description:excerpt(200,true)"
 // @Success 200 {object} map[string]interface{}
 // @Router /api/collections/{collection}/records/{id} [get]
 func (p *PocketBaseController) GetRecord(c *gin.Context) {
-	collection := c.Param("collection")
-	id := c.Param("id")
-	expand := c.Query("expand")
-	
-	// Route to appropriate handler based on collection
-	switch collection {
-	case "users":
-		p.getUser(c, id, expand)
-	case "categories":
-		p.getCategory(c, id, expand)
-	case "subcategories":
-		p.getSubcategory(c, id, expand)
-	case "stores":
-		p.getStore(c, id, expand)
-	case "products":
-		p.getProduct(c, id, expand)
-	case "carts":
-		p.getCart(c, id, expand)
-	case "cart_items":
-		p.getCartItem(c, id, expand)
-	case "addresses":
-		p.getAddress(c, id, expand)
-	case "orders":
-		p.getOrder(c, id, expand)
-	case "customers":
-		p.getCustomer(c, id, expand)
-	case "notifications":
-		p.getNotification(c, id, expand)
-	default:
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": fmt.Sprintf("Collection '%s' not found", collection),
-		})
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
 	}
+
+	dbMap := db.GetDB()
+	request := newRecordRequest(c, nil)
+
+	record, err := request.view(dbMap.Db, collection, c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch record.")
+		return
+	}
+
+	respondRecord(c, opts, dbMap.Db, request, collection, record)
 }
 
 // CreateRecord godoc
 // @Summary Create record
-// @Description Create a new record in specified collection
+// @Description Create a new record in specified collection. Send multipart/form-data to upload files.
 // @Tags collections
-// @Accept json
+// @Accept json,mpfd
 // @Produce json
 // @Param collection path string true "Collection name"
 // @Param body body map[string]interface{} true "Record data"
+// @Param expand query string false "Expand relations"
+// @Param fields query string false "Fields to return, e.g. id,name,description:excerpt(200,true)"
 // @Success 200 {object} map[string]interface{}
 // @Router /api/collections/{collection}/records [post]
 func (p *PocketBaseController) CreateRecord(c *gin.Context) {
-	collection := c.Param("collection")
-	
-	var data map[string]interface{}
-	if err := c.ShouldBindJSON(&data); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid JSON data",
-		})
+	collection, ok := findCollection(c)
+	if !ok {
 		return
 	}
-	
-	// Route to appropriate handler based on collection
-	switch collection {
-	case "users":
-		p.createUser(c, data)
-	case "categories":
-		p.createCategory(c, data)
-	case "subcategories":
-		p.createSubcategory(c, data)
-	case "stores":
-		p.createStore(c, data)
-	case "products":
-		p.createProduct(c, data)
-	case "carts":
-		p.createCart(c, data)
-	case "cart_items":
-		p.createCartItem(c, data)
-	case "addresses":
-		p.createAddress(c, data)
-	case "orders":
-		p.createOrder(c, data)
-	case "customers":
-		p.createCustomer(c, data)
-	case "notifications":
-		p.createNotification(c, data)
-	default:
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": fmt.Sprintf("Collection '%s' not found", collection),
-		})
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
+	}
+
+	data, files, err := parseRecordBody(c)
+	if err != nil {
+		respondError(c, err, "")
+		return
+	}
+
+	dbMap := db.GetDB()
+	request := newRecordRequest(c, data)
+	request.files = files
+
+	var record models.Record
+	err = inTransaction(func(tx *sql.Tx) (err error) {
+		record, err = request.create(tx, collection, data)
+		return err
+	})
+	if err != nil {
+		request.rolledBack()
+		respondError(c, err, "Failed to create record.")
+		return
 	}
+	request.committed()
+
+	respondRecord(c, opts, dbMap.Db, request, collection, record)
 }
 
 // UpdateRecord godoc
 // @Summary Update record
-// @Description Update an existing record in specified collection
+// @Description Update an existing record in specified collection. Send multipart/form-data to upload files, field+ to append them and field- to remove files by name.
 // @Tags collections
-// @Accept json
+// @Accept json,mpfd
 // @Produce json
 // @Param collection path string true "Collection name"
 // @Param id path string true "Record ID"
 // @Param body body map[string]interface{} true "Record data"
+// @Param If-Match header string false "ETag of the record version to update"
+// @Param expand query string false "Expand relations"
+// @Param fields query string false "Fields to return, e.g. id,name,description:excerpt(200,true)"
 // @Success 200 {object} map[string]interface{}
+// @Failure 412 {object} models.PBErrorResponse
+// @Failure 428 {object} models.PBErrorResponse
 // @Router /api/collections/{collection}/records/{id} [patch]
 func (p *PocketBaseController) UpdateRecord(c *gin.Context) {
-	collection := c.Param("collection")
-	id := c.Param("id")
-	
-	var data map[string]interface{}
-	if err := c.ShouldBindJSON(&data); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid JSON data",
-		})
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
+	}
+
+	data, files, err := parseRecordBody(c)
+	if err != nil {
+		respondError(c, err, "")
 		return
 	}
-	
-	// Route to appropriate handler based on collection
-	switch collection {
-	case "users":
-		p.updateUser(c, id, data)
-	case "categories":
-		p.updateCategory(c, id, data)
-	case "subcategories":
-		p.updateSubcategory(c, id, data)
-	case "stores":
-		p.updateStore(c, id, data)
-	case "products":
-		p.updateProduct(c, id, data)
-	case "carts":
-		p.updateCart(c, id, data)
-	case "cart_items":
-		p.updateCartItem(c, id, data)
-	case "addresses":
-		p.updateAddress(c, id, data)
-	case "orders":
-		p.updateOrder(c, id, data)
-	case "customers":
-		p.updateCustomer(c, id, data)
-	case "notifications":
-		p.updateNotification(c, id, data)
-	default:
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": fmt.Sprintf("Collection '%s' not found", collection),
-		})
+
+	dbMap := db.GetDB()
+	request := newRecordRequest(c, data)
+	request.files = files
+
+	var record models.Record
+	err = inTransaction(func(tx *sql.Tx) (err error) {
+		record, err = request.update(tx, collection, c.Param("id"), data)
+		return err
+	})
+	if err != nil {
+		request.rolledBack()
+		respondError(c, err, "Failed to update record.")
+		return
 	}
+	request.committed()
+
+	respondRecord(c, opts, dbMap.Db, request, collection, record)
 }
 
 // DeleteRecord godoc
@@ -253,41 +301,30 @@ func (p *PocketBaseController) UpdateRecord(c *gin.Context) {
 // @Produce json
 // @Param collection path string true "Collection name"
 // @Param id path string true "Record ID"
+// @Param If-Match header string false "ETag of the record version to delete"
 // @Success 204
+// @Failure 412 {object} models.PBErrorResponse
+// @Failure 428 {object} models.PBErrorResponse
 // @Router /api/collections/{collection}/records/{id} [delete]
 func (p *PocketBaseController) DeleteRecord(c *gin.Context) {
-	collection := c.Param("collection")
-	id := c.Param("id")
-	
-	// Route to appropriate handler based on collection
-	switch collection {
-	case "users":
-		p.deleteUser(c, id)
-	case "categories":
-		p.deleteCategory(c, id)
-	case "subcategories":
-		p.deleteSubcategory(c, id)
-	case "stores":
-		p.deleteStore(c, id)
-	case "products":
-		p.deleteProduct(c, id)
-	case "carts":
-		p.deleteCart(c, id)
-	case "cart_items":
-		p.deleteCartItem(c, id)
-	case "addresses":
-		p.deleteAddress(c, id)
-	case "orders":
-		p.deleteOrder(c, id)
-	case "customers":
-		p.deleteCustomer(c, id)
-	case "notifications":
-		p.deleteNotification(c, id)
-	default:
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": fmt.Sprintf("Collection '%s' not found", collection),
-		})
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	request := newRecordRequest(c, nil)
+
+	err := inTransaction(func(tx *sql.Tx) error {
+		return request.delete(tx, collection, c.Param("id"))
+	})
+	if err != nil {
+		request.rolledBack()
+		respondError(c, err, "Failed to delete record.")
+		return
 	}
+	request.committed()
+
+	c.JSON(http.StatusNoContent, nil)
 }
 
 // Helper functions
@@ -296,68 +333,136 @@ func generateID() string {
 	return strings.ReplaceAll(uuid.New().String(), "-", "")[:15]
 }
 
-func setBaseRecord(record *models.BaseRecord, collectionName string) {
-	now := time.Now()
-	if record.ID == "" {
-		record.ID = generateID()
+// findCollection resolves the :collection route parameter, responding with
+// 404 when it is not registered
+func findCollection(c *gin.Context) (*models.Collection, bool) {
+	name := c.Param("collection")
+	collection, ok := models.FindCollection(name)
+	if !ok {
+		respondError(c, newAPIError(http.StatusNotFound, fmt.Sprintf("Collection '%s' not found.", name)), "")
+		return nil, false
+	}
+	return collection, true
+}
+
+// responseOptions are the expand and fields query parameters shared by all
+// record responses
+type responseOptions struct {
+	expand expandTree
+	fields *fieldsTree
+}
+
+// parseResponseOptions reads the expand and fields parameters, responding
+// with 400 when fields is invalid
+func parseResponseOptions(c *gin.Context) (responseOptions, bool) {
+	opts, err := responseOptionsFromQuery(c.Request.URL.Query())
+	if err != nil {
+		respondInvalidQuery(c, "fields", err)
+		return responseOptions{}, false
 	}
-	if record.Created.IsZero() {
-		record.Created = now
+	return opts, true
+}
+
+func responseOptionsFromQuery(query url.Values) (responseOptions, error) {
+	fields, err := parseFields(query.Get("fields"))
+	if err != nil {
+		return responseOptions{}, err
 	}
-	record.Updated = now
-	record.CollectionID = collectionName
-	record.CollectionName = collectionName
+	return responseOptions{expand: parseExpand(query.Get("expand")), fields: fields}, nil
 }
 
-func buildSortClause(sort string) string {
-	if sort == "" {
-		return ""
-	}
-	
-	sortFields := strings.Split(sort, ",")
-	var clauses []string
-	
-	for _, field := range sortFields {
-		field = strings.TrimSpace(field)
-		if strings.HasPrefix(field, "-") {
-			clauses = append(clauses, field[1:]+" DESC")
-		} else if strings.HasPrefix(field, "+") {
-			clauses = append(clauses, field[1:]+" ASC")
-		} else {
-			clauses = append(clauses, field+" ASC")
+// prepare expands the relations visible to request and applies the fields
+// projection
+func (o responseOptions) prepare(q querier, request *recordRequest, collection *models.Collection, records []models.Record) ([]models.Record, error) {
+	if err := expandRecords(q, request, collection, records, o.expand); err != nil {
+		return nil, err
+	}
+	if o.fields != nil {
+		for i, record := range records {
+			records[i] = o.fields.projectRecord(record)
 		}
 	}
-	
-	return "ORDER BY " + strings.Join(clauses, ", ")
+	return records, nil
+}
+
+// respondRecord writes a single record with expand and fields applied, and
+// its version as ETag
+func respondRecord(c *gin.Context, opts responseOptions, q querier, request *recordRequest, collection *models.Collection, record models.Record) {
+	etag := recordETag(record)
+	records, err := opts.prepare(q, request, collection, []models.Record{record})
+	if err != nil {
+		respondError(c, err, "Failed to expand record.")
+		return
+	}
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	c.JSON(http.StatusOK, records[0])
 }
 
-func buildFilterClause(filter string) (string, []interface{}) {
-	if filter == "" {
-		return "", nil
-	}
-	
-	// This is a simplified filter parser
-	// In production, you'd need a more robust parser for PocketBase filter syntax
-	parts := strings.Split(filter, "&&")
-	var conditions []string
-	var args []interface{}
-	
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if strings.Contains(part, "=") {
-			keyValue := strings.SplitN(part, "=", 2)
-			if len(keyValue) == 2 {
-				key := strings.TrimSpace(keyValue[0])
-				value := strings.Trim(strings.TrimSpace(keyValue[1]), "\"'")
-				conditions = append(conditions, key+" = ?")
-				args = append(args, value)
+// listFilter adds the collection's default filter unless the client filter
+// already references one of the fields it uses
+func listFilter(collection *models.Collection, filter string) string {
+	if collection.DefaultFilter == "" {
+		return filter
+	}
+
+	used := filterIdentifiers(filter)
+	for _, name := range filterIdentifiers(collection.DefaultFilter) {
+		for _, u := range used {
+			if u == name {
+				return filter
 			}
 		}
 	}
-	
-	if len(conditions) > 0 {
-		return "WHERE " + strings.Join(conditions, " AND "), args
+
+	if strings.TrimSpace(filter) == "" {
+		return collection.DefaultFilter
+	}
+	return "(" + filter + ") && (" + collection.DefaultFilter + ")"
+}
+
+// buildFilterClause converts a PocketBase filter expression into a WHERE
+// clause. The expression is wrapped in parentheses so callers can safely
+// append extra conditions with andWhere.
+func buildFilterClause(filter string, resolve filterFieldResolver) (string, []interface{}, error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", nil, nil
+	}
+
+	expr, args, err := filterToSQL(filter, resolve)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "WHERE (" + expr + ")", args, nil
+}
+
+// andWhere adds condition to a WHERE clause, which may be empty
+func andWhere(whereClause, condition string) string {
+	if whereClause == "" {
+		return "WHERE " + condition
+	}
+	return whereClause + " AND " + condition
+}
+
+// inTransaction runs fn in a database transaction that is committed when fn
+// succeeds and rolled back otherwise
+func inTransaction(fn func(tx *sql.Tx) error) error {
+	tx, err := db.GetDB().Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
 	}
-	
-	return "", nil
-}
\ No newline at end of file
+	return tx.Commit()
+}
+
+// respondInvalidQuery writes a PocketBase-style 400 response for an unusable
+// query parameter.
+func respondInvalidQuery(c *gin.Context, param string, err error) {
+	respondError(c, newAPIError(http.StatusBadRequest, fmt.Sprintf("Invalid %s parameter: %s.", param, err.Error())), "")
+}
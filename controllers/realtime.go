@@ -0,0 +1,393 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/forms"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PocketBase realtime API.
+//
+// Clients open a Server-Sent Events stream with GET /api/realtime, receive a
+// PB_CONNECT event holding their client id and pick topics with POST
+// /api/realtime. A topic is "<collection>/*" for every record of a collection
+// or "<collection>/<recordId>" for a single record, optionally followed by
+// "?options=" and a JSON {"query": {...}, "headers": {...}} object as sent by
+// the PocketBase SDKs. Events are named after the subscribed topic and carry
+// {"action": ..., "record": ...}.
+
+const (
+	realtimeActionCreate = "create"
+	realtimeActionUpdate = "update"
+	realtimeActionDelete = "delete"
+)
+
+// realtimeIdleTimeout closes streams without events; the SDKs reconnect and
+// resubscribe automatically
+const realtimeIdleTimeout = 5 * time.Minute
+
+// realtimeBufferSize is the number of events queued for a client before new
+// events are dropped
+const realtimeBufferSize = 100
+
+const (
+	maxRealtimeSubscriptions = 1000
+	maxRealtimeTopicLength   = 2500
+)
+
+// realtimeMessage is a single Server-Sent Event
+type realtimeMessage struct {
+	name string
+	data []byte
+}
+
+// realtimeSubscription is a parsed topic
+type realtimeSubscription struct {
+	collection string // collection name or id
+	recordID   string // "*" for every record
+	query      url.Values
+	headers    http.Header
+	options    responseOptions
+}
+
+// realtimeClient is a connected event stream
+type realtimeClient struct {
+	id       string
+	messages chan realtimeMessage
+
+	mu            sync.RWMutex
+	subscriptions map[string]realtimeSubscription
+	auth          models.Record
+	admin         bool
+}
+
+// realtimeBroker tracks the connected clients
+type realtimeBroker struct {
+	mu      sync.RWMutex
+	clients map[string]*realtimeClient
+}
+
+var realtimeClients = &realtimeBroker{clients: map[string]*realtimeClient{}}
+
+func (b *realtimeBroker) register() *realtimeClient {
+	client := &realtimeClient{
+		id:            strings.ReplaceAll(uuid.New().String(), "-", ""),
+		messages:      make(chan realtimeMessage, realtimeBufferSize),
+		subscriptions: map[string]realtimeSubscription{},
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clients[client.id] = client
+	return client
+}
+
+func (b *realtimeBroker) unregister(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.clients, id)
+}
+
+func (b *realtimeBroker) client(id string) (*realtimeClient, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	client, ok := b.clients[id]
+	return client, ok
+}
+
+func (b *realtimeBroker) snapshot() []*realtimeClient {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	clients := make([]*realtimeClient, 0, len(b.clients))
+	for _, client := range b.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// recordEvent returns the function announcing a record change once its
+// transaction committed. Each subscriber's rules are checked then, outside
+// of the write transaction, against the committed record; deleted records
+// are matched in the trash.
+func (b *realtimeBroker) recordEvent(action string, collection *models.Collection, record models.Record) func() {
+	return func() {
+		if action != realtimeActionCreate {
+			b.refreshAuth(action, collection, record)
+		}
+
+		q := db.GetDB().Db
+		for _, client := range b.snapshot() {
+			for topic, sub := range client.subscriptionsFor(collection, record.ID()) {
+				message, ok, err := client.recordMessage(q, topic, sub, action, collection, record)
+				if err != nil {
+					log.Printf("realtime: %s %s/%s for client %s: %v", action, collection.Name, record.ID(), client.id, err)
+					continue
+				}
+				if ok {
+					client.send(message)
+				}
+			}
+		}
+	}
+}
+
+// refreshAuth keeps the auth state of the clients authenticated as an
+// updated or deleted record current
+func (b *realtimeBroker) refreshAuth(action string, collection *models.Collection, record models.Record) {
+	for _, client := range b.snapshot() {
+		client.mu.Lock()
+		if client.auth != nil && client.auth.ID() == record.ID() && client.auth["collectionName"] == collection.Name {
+			if action == realtimeActionDelete {
+				client.auth = nil
+			} else {
+				client.auth = copyRecord(record)
+			}
+		}
+		client.mu.Unlock()
+	}
+}
+
+// subscriptionsFor returns the subscriptions of c matching a record
+func (c *realtimeClient) subscriptionsFor(collection *models.Collection, id string) map[string]realtimeSubscription {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	matching := map[string]realtimeSubscription{}
+	for topic, sub := range c.subscriptions {
+		if (sub.collection == collection.Name || sub.collection == collection.ID) && (sub.recordID == "*" || sub.recordID == id) {
+			matching[topic] = sub
+		}
+	}
+	return matching
+}
+
+// request returns the recordRequest used to check the rules of sub
+func (c *realtimeClient) request(sub realtimeSubscription) *recordRequest {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &recordRequest{auth: c.auth, admin: c.admin, method: http.MethodGet, query: sub.query, headers: sub.headers}
+}
+
+// recordMessage builds the event for a record when the client may see it:
+// wildcard topics follow the list rule and the filter option, single record
+// topics the view rule
+func (c *realtimeClient) recordMessage(q querier, topic string, sub realtimeSubscription, action string, collection *models.Collection, record models.Record) (realtimeMessage, bool, error) {
+	request := c.request(sub)
+
+	rule := collection.ViewRule
+	if sub.recordID == "*" {
+		rule = collection.ListRule
+	}
+	condition, args, allowed, err := request.ruleCondition(collection, rule)
+	if err != nil || !allowed {
+		return realtimeMessage{}, false, err
+	}
+
+	if filter := sub.query.Get("filter"); sub.recordID == "*" && strings.TrimSpace(filter) != "" {
+		expr, filterArgs, err := filterToSQL(filter, request.filterResolver(collection))
+		if err != nil {
+			return realtimeMessage{}, false, nil
+		}
+		if condition != "" {
+			condition += " AND "
+		}
+		condition += "(" + expr + ")"
+		args = append(args, filterArgs...)
+	}
+
+	matches, err := eventRecordMatches(q, action, collection, record.ID(), condition, args)
+	if err != nil || !matches {
+		return realtimeMessage{}, false, err
+	}
+
+	records, err := sub.options.prepare(q, request, collection, []models.Record{copyRecord(record)})
+	if err != nil {
+		return realtimeMessage{}, false, err
+	}
+	data, err := json.Marshal(gin.H{"action": action, "record": records[0]})
+	if err != nil {
+		return realtimeMessage{}, false, err
+	}
+	return realtimeMessage{name: topic, data: data}, true, nil
+}
+
+// eventRecordMatches is recordMatches for the record of a committed event,
+// looking for deleted records in the trash
+func eventRecordMatches(q querier, action string, collection *models.Collection, id, condition string, args []interface{}) (bool, error) {
+	if action != realtimeActionDelete {
+		return recordMatches(q, collection, id, condition, args)
+	}
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%[1]s] WHERE [%[1]s].[id] = ? AND [%[1]s].[deleted] IS NOT NULL", collection.Table)
+	if condition != "" {
+		query += " AND " + condition
+	}
+	var matches bool
+	err := q.QueryRow(query+")", append([]interface{}{id}, args...)...).Scan(&matches)
+	return matches, err
+}
+
+// send queues a message without blocking the writer of the record
+func (c *realtimeClient) send(message realtimeMessage) {
+	select {
+	case c.messages <- message:
+	default:
+		log.Printf("realtime: client %s is not keeping up, dropping %s event", c.id, message.name)
+	}
+}
+
+// parseRealtimeTopic parses a subscription topic
+func parseRealtimeTopic(topic string) (realtimeSubscription, error) {
+	if len(topic) > maxRealtimeTopicLength || strings.ContainsAny(topic, "\r\n") {
+		return realtimeSubscription{}, errors.New("invalid topic")
+	}
+
+	path, rawQuery, _ := strings.Cut(topic, "?")
+	collection, recordID, ok := strings.Cut(path, "/")
+	if !ok || collection == "" || recordID == "" || strings.Contains(recordID, "/") {
+		return realtimeSubscription{}, errors.New("must be <collection>/* or <collection>/<recordId>")
+	}
+	sub := realtimeSubscription{collection: collection, recordID: recordID, query: url.Values{}, headers: http.Header{}}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return realtimeSubscription{}, errors.New("invalid topic query")
+	}
+	if raw := values.Get("options"); raw != "" {
+		var options struct {
+			Query   map[string]interface{} `json:"query"`
+			Headers map[string]string      `json:"headers"`
+		}
+		if err := json.Unmarshal([]byte(raw), &options); err != nil {
+			return realtimeSubscription{}, errors.New("invalid options")
+		}
+		for key, value := range options.Query {
+			sub.query.Set(key, fmt.Sprint(value))
+		}
+		for key, value := range options.Headers {
+			sub.headers.Set(key, value)
+		}
+	}
+
+	sub.options, err = responseOptionsFromQuery(sub.query)
+	if err != nil {
+		return realtimeSubscription{}, fmt.Errorf("invalid fields option: %w", err)
+	}
+	return sub, nil
+}
+
+// RealtimeController serves the realtime API
+type RealtimeController struct{}
+
+// Connect godoc
+// @Summary Realtime event stream
+// @Description Open a Server-Sent Events stream. The first event, PB_CONNECT, holds the client id used to subscribe.
+// @Tags realtime
+// @Produce text/event-stream
+// @Success 200
+// @Router /api/realtime [get]
+func (ctl RealtimeController) Connect(c *gin.Context) {
+	client := realtimeClients.register()
+	defer realtimeClients.unregister(client.id)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-store")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	connect, _ := json.Marshal(gin.H{"clientId": client.id})
+	if err := writeRealtimeMessage(c.Writer, client.id, realtimeMessage{name: "PB_CONNECT", data: connect}); err != nil {
+		return
+	}
+
+	idle := time.NewTimer(realtimeIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-idle.C:
+			return
+		case message := <-client.messages:
+			if err := writeRealtimeMessage(c.Writer, client.id, message); err != nil {
+				return
+			}
+			idle.Reset(realtimeIdleTimeout)
+		}
+	}
+}
+
+func writeRealtimeMessage(w gin.ResponseWriter, id string, message realtimeMessage) error {
+	if _, err := fmt.Fprintf(w, "id:%s\nevent:%s\ndata:%s\n\n", id, message.name, message.data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+// Subscribe godoc
+// @Summary Set realtime subscriptions
+// @Description Replace the topics of a realtime client, e.g. "orders/*" or "orders/RECORD_ID". The client takes the authorization of this request.
+// @Tags realtime
+// @Accept json
+// @Param body body forms.RealtimeSubscribeForm true "Subscriptions"
+// @Success 204
+// @Router /api/realtime [post]
+func (ctl RealtimeController) Subscribe(c *gin.Context) {
+	var form forms.RealtimeSubscribeForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		respondError(c, errInvalidBody, "")
+		return
+	}
+
+	client, ok := realtimeClients.client(form.ClientID)
+	if !ok {
+		respondError(c, newAPIError(http.StatusNotFound, "Missing or invalid client id."), "")
+		return
+	}
+
+	if len(form.Subscriptions) > maxRealtimeSubscriptions {
+		respondError(c, newFieldError("subscriptions", codeInvalidValue,
+			fmt.Sprintf("Must contain at most %d topics.", maxRealtimeSubscriptions)), "Failed to subscribe.")
+		return
+	}
+	subscriptions := map[string]realtimeSubscription{}
+	for i, topic := range form.Subscriptions {
+		sub, err := parseRealtimeTopic(topic)
+		if err != nil {
+			respondError(c, newFieldError(fmt.Sprintf("subscriptions.%d", i), codeInvalidValue, "Invalid topic: "+err.Error()+"."), "Failed to subscribe.")
+			return
+		}
+		subscriptions[topic] = sub
+	}
+
+	auth, admin := requestAuthRecord(c), IsAdmin(c)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// A client keeps the identity it subscribed with first, so a leaked
+	// client id cannot be taken over by another user
+	if (client.auth != nil && (auth == nil || auth.ID() != client.auth.ID() || auth["collectionName"] != client.auth["collectionName"])) ||
+		(client.admin && !admin) {
+		respondError(c, newAPIError(http.StatusForbidden, "The current and the previous request authorization don't match."), "")
+		return
+	}
+
+	client.auth, client.admin = auth, admin
+	client.subscriptions = subscriptions
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subscribeTestClient registers a realtime client authenticated as the user
+// id, or as a guest when it is empty, with the given topics
+func subscribeTestClient(t *testing.T, userID string, topics ...string) *realtimeClient {
+	t.Helper()
+
+	client := realtimeClients.register()
+	t.Cleanup(func() { realtimeClients.unregister(client.id) })
+	if userID != "" {
+		client.auth = models.Record{"id": userID, "collectionName": "users"}
+	}
+	for _, topic := range topics {
+		sub, err := parseRealtimeTopic(topic)
+		require.NoError(t, err)
+		client.subscriptions[topic] = sub
+	}
+	return client
+}
+
+// receivedActions returns the actions of the events queued for client
+func receivedActions(t *testing.T, client *realtimeClient) []string {
+	t.Helper()
+
+	actions := []string{}
+	for {
+		select {
+		case message := <-client.messages:
+			var event struct {
+				Action string `json:"action"`
+			}
+			require.NoError(t, json.Unmarshal(message.data, &event))
+			actions = append(actions, message.name+" "+event.Action)
+		default:
+			return actions
+		}
+	}
+}
+
+func TestRealtimeEventsFollowRules(t *testing.T) {
+	conn := openTestDB(t)
+	r := newTestRouter()
+	r.POST("/api/collections/:collection/records", new(PocketBaseController).CreateRecord)
+	r.DELETE("/api/collections/:collection/records/:id", new(PocketBaseController).DeleteRecord)
+
+	_, err := conn.Exec("INSERT INTO users (id, email, username) VALUES ('user_other', 'other@example.com', 'other')")
+	require.NoError(t, err)
+	owner := subscribeTestClient(t, "user_sample_123", "carts/*")
+	other := subscribeTestClient(t, "user_other", "carts/*")
+	guest := subscribeTestClient(t, "", "carts/*")
+	matching := subscribeTestClient(t, "user_sample_123", `carts/*?options={"query":{"filter":"session_id = 'app'"}}`)
+	filtered := subscribeTestClient(t, "user_sample_123", `carts/*?options={"query":{"filter":"session_id = 'web'"}}`)
+
+	var cart map[string]interface{}
+	status := serveJSON(t, r, http.MethodPost, "/api/collections/carts/records", testAdminToken,
+		map[string]interface{}{"user": "user_sample_123", "session_id": "app"}, &cart)
+	require.Equal(t, http.StatusOK, status, cart)
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/carts/records/"+cart["id"].(string), testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	assert.Equal(t, []string{"carts/* create", "carts/* delete"}, receivedActions(t, owner))
+	assert.Empty(t, receivedActions(t, other), "the list rule hides the carts of other users")
+	assert.Empty(t, receivedActions(t, guest))
+	assert.Len(t, receivedActions(t, matching), 2)
+	assert.Empty(t, receivedActions(t, filtered), "the filter option does not match")
+}
+
+func TestRealtimeEventsWaitForCommit(t *testing.T) {
+	openTestDB(t)
+	r := newTestRouter()
+	r.POST("/api/batch", new(PocketBaseController).Batch)
+
+	client := subscribeTestClient(t, "", "categories/*")
+
+	status := serveJSON(t, r, http.MethodPost, "/api/batch", testAdminToken, map[string]interface{}{
+		"requests": []map[string]interface{}{
+			{"method": "POST", "url": "/api/collections/categories/records", "body": map[string]interface{}{"name": "Decks", "slug": "decks"}},
+			{"method": "POST", "url": "/api/collections/categories/records", "body": map[string]interface{}{"name": "Sneakers", "slug": "shoes"}},
+		},
+	}, nil)
+	require.Equal(t, http.StatusBadRequest, status)
+	assert.Empty(t, receivedActions(t, client), "a rolled back batch announces nothing")
+
+	status = serveJSON(t, r, http.MethodPost, "/api/batch", testAdminToken, map[string]interface{}{
+		"requests": []map[string]interface{}{
+			{"method": "POST", "url": "/api/collections/categories/records", "body": map[string]interface{}{"name": "Decks", "slug": "decks"}},
+		},
+	}, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"categories/* create"}, receivedActions(t, client))
+}
@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/forms"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Auth collections.
+//
+// Records of auth collections, such as users, sign in with their email or
+// username and password and receive a record auth token for the
+// Authorization header of later requests. Passwords are stored as bcrypt
+// hashes in password fields, which never leave the database: records,
+// filters, sorts and the audit log leave them out.
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores the bytes past 72
+	maxPasswordLength = 71
+)
+
+var (
+	errInvalidCredentials = newAPIError(http.StatusBadRequest, "Failed to authenticate.")
+	errAuthRequired       = newAPIError(http.StatusUnauthorized, "The request requires valid record authorization token to be set.")
+)
+
+// dummyPasswordHash is compared against when the identity matches no record,
+// so that failed sign-ins take as long whether the record exists or not
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// identityFieldNames are the fields auth records sign in with, when the
+// collection has them
+var identityFieldNames = []string{"email", "username"}
+
+// RecordAuthController signs in the records of auth collections
+type RecordAuthController struct{}
+
+// AuthWithPassword godoc
+// @Summary Authenticate with password
+// @Description Sign in a record of an auth collection with its email or username and password
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param collection path string true "Auth collection name"
+// @Param body body forms.AuthWithPasswordForm true "Credentials"
+// @Param expand query string false "Expand relations of the record"
+// @Param fields query string false "Fields to return"
+// @Success 200 {object} models.PBAuthResponse
+// @Failure 400 {object} models.PBErrorResponse
+// @Router /api/collections/{collection}/auth-with-password [post]
+func (ctl RecordAuthController) AuthWithPassword(c *gin.Context) {
+	collection, ok := findAuthCollection(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
+	}
+
+	var form forms.AuthWithPasswordForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		respondError(c, errInvalidBody, "")
+		return
+	}
+	errs := fieldErrors{}
+	if strings.TrimSpace(form.Identity) == "" {
+		errs.Add("identity", codeRequired, "Cannot be blank.")
+	}
+	if form.Password == "" {
+		errs.Add("password", codeRequired, "Cannot be blank.")
+	}
+	if len(errs) > 0 {
+		respondError(c, errs, "Failed to authenticate.")
+		return
+	}
+
+	conn := db.GetDB().Db
+	record, err := authenticateRecord(conn, collection, strings.TrimSpace(form.Identity), form.Password)
+	if err != nil {
+		respondError(c, err, "Failed to authenticate.")
+		return
+	}
+
+	respondAuth(c, opts, conn, collection, record)
+}
+
+// AuthRefresh godoc
+// @Summary Refresh auth token
+// @Description Return a new auth token for the record authenticated by the Authorization header, along with its current data
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param collection path string true "Auth collection name"
+// @Param expand query string false "Expand relations of the record"
+// @Param fields query string false "Fields to return"
+// @Success 200 {object} models.PBAuthResponse
+// @Failure 401 {object} models.PBErrorResponse
+// @Router /api/collections/{collection}/auth-refresh [post]
+func (ctl RecordAuthController) AuthRefresh(c *gin.Context) {
+	collection, ok := findAuthCollection(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
+	}
+
+	record := requestAuthRecord(c)
+	if record == nil || record["collectionName"] != collection.Name {
+		respondError(c, errAuthRequired, "")
+		return
+	}
+
+	respondAuth(c, opts, db.GetDB().Db, collection, record)
+}
+
+// AuthMethods godoc
+// @Summary List auth methods
+// @Description List the sign-in methods of an auth collection
+// @Tags auth
+// @Produce json
+// @Param collection path string true "Auth collection name"
+// @Success 200 {object} models.PBAuthMethodsResponse
+// @Router /api/collections/{collection}/auth-methods [get]
+func (ctl RecordAuthController) AuthMethods(c *gin.Context) {
+	collection, ok := findAuthCollection(c)
+	if !ok {
+		return
+	}
+
+	names := []string{}
+	for _, field := range identityFields(collection) {
+		names = append(names, field.Name)
+	}
+	_, hasPassword := passwordField(collection)
+
+	c.JSON(http.StatusOK, models.PBAuthMethodsResponse{
+		Password: models.PBPasswordAuthMethod{Enabled: hasPassword && len(names) > 0, IdentityFields: names},
+		OAuth2:   models.PBOAuth2AuthMethod{Providers: []interface{}{}},
+	})
+}
+
+// findAuthCollection loads the auth collection named in the URL, writing a
+// 404 response when there is none
+func findAuthCollection(c *gin.Context) (*models.Collection, bool) {
+	collection, ok := findCollection(c)
+	if !ok {
+		return nil, false
+	}
+	if !collection.IsAuth() {
+		respondError(c, newAPIError(http.StatusNotFound, fmt.Sprintf("Collection '%s' is not an auth collection.", collection.Name)), "")
+		return nil, false
+	}
+	return collection, true
+}
+
+// respondAuth writes a new auth token for record along with the record,
+// expanded as seen by the record itself
+func respondAuth(c *gin.Context, opts responseOptions, q querier, collection *models.Collection, record models.Record) {
+	tokenKey, err := recordTokenKey(q, collection, record.ID())
+	if err != nil {
+		respondError(c, err, "Failed to create auth token.")
+		return
+	}
+	token, err := models.NewRecordAuthToken(collection, record.ID(), tokenKey)
+	if err != nil {
+		respondError(c, err, "Failed to create auth token.")
+		return
+	}
+
+	request := newRecordRequest(c, nil)
+	request.auth = record
+	records, err := opts.prepare(q, request, collection, []models.Record{record})
+	if err != nil {
+		respondError(c, err, "Failed to expand record.")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PBAuthResponse{Token: token, Record: records[0]})
+}
+
+// recordTokenKey returns the token key of a record of an auth collection,
+// returning sql.ErrNoRows when the record does not exist or is in the trash
+func recordTokenKey(q querier, collection *models.Collection, id string) (string, error) {
+	var key sql.NullString
+	query := fmt.Sprintf("SELECT [%s] FROM [%s] WHERE [id] = ? AND %s", models.TokenKeyColumn, collection.Table, liveCondition(collection))
+	err := q.QueryRow(query, id).Scan(&key)
+	return key.String, err
+}
+
+// tokenKeyUpdate returns the SET clause, and its arguments, renewing the
+// token key of an auth record updated with data, which signs the record out
+// of the sessions opened with its previous credentials. A new password
+// always renews the key, an email only when it differs from the current one.
+func tokenKeyUpdate(collection *models.Collection, data map[string]interface{}) (string, []interface{}) {
+	if !collection.IsAuth() {
+		return "", nil
+	}
+	set := fmt.Sprintf("[%s] = ?", models.TokenKeyColumn)
+	if field, ok := passwordField(collection); ok {
+		if password, _ := data[field.Name].(string); password != "" {
+			return set, []interface{}{models.NewTokenKey()}
+		}
+	}
+	if field, ok := collection.Field("email"); ok && field.Type == models.FieldTypeEmail {
+		if email, ok := data[field.Name].(string); ok {
+			set = fmt.Sprintf("[%s] = CASE WHEN [%s] = ? COLLATE NOCASE THEN [%[1]s] ELSE ? END", models.TokenKeyColumn, field.ColumnName())
+			return set, []interface{}{email, models.NewTokenKey()}
+		}
+	}
+	return "", nil
+}
+
+// identityFields returns the fields of collection records sign in with
+func identityFields(collection *models.Collection) []models.Field {
+	var fields []models.Field
+	for _, name := range identityFieldNames {
+		field, ok := collection.Field(name)
+		if ok && (field.Type == models.FieldTypeText || field.Type == models.FieldTypeEmail) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// passwordField returns the password field of an auth collection
+func passwordField(collection *models.Collection) (models.Field, bool) {
+	for _, field := range collection.Fields {
+		if field.Type == models.FieldTypePassword {
+			return field, true
+		}
+	}
+	return models.Field{}, false
+}
+
+// authenticateRecord finds the record of collection whose email or username
+// is identity and checks its password, returning errInvalidCredentials when
+// either does not match
+func authenticateRecord(q querier, collection *models.Collection, identity, password string) (models.Record, error) {
+	field, hasPassword := passwordField(collection)
+	identities := identityFields(collection)
+	if !hasPassword || len(identities) == 0 {
+		return nil, errInvalidCredentials
+	}
+
+	conditions := make([]string, len(identities))
+	args := make([]interface{}, len(identities))
+	for i, f := range identities {
+		conditions[i] = fmt.Sprintf("[%s].[%s] = ?", collection.Table, f.ColumnName())
+		if f.Type == models.FieldTypeEmail {
+			conditions[i] += " COLLATE NOCASE"
+		}
+		args[i] = identity
+	}
+	query := fmt.Sprintf("SELECT [id], [%s] FROM [%s] WHERE (%s) AND %s LIMIT 1",
+		field.ColumnName(), collection.Table, strings.Join(conditions, " OR "), liveCondition(collection))
+
+	var id string
+	var hash sql.NullString
+	err := q.QueryRow(query, args...).Scan(&id, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hash.Valid || bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(password)) != nil {
+		return nil, errInvalidCredentials
+	}
+
+	return findRecord(q, collection, id)
+}
+
+// passwordValue validates a new password and returns its hash
+func passwordValue(field models.Field, raw interface{}) (interface{}, error) {
+	var password string
+	switch v := raw.(type) {
+	case nil:
+	case string:
+		password = v
+	default:
+		return nil, newFieldError(field.Name, codeInvalidValue, "Must be a string.")
+	}
+	if password == "" {
+		return nil, nil
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, newFieldError(field.Name, codeLengthOutOfRange,
+			fmt.Sprintf("Must be between %d and %d characters.", minPasswordLength, maxPasswordLength))
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return string(hash), nil
+}
+
+// validatePasswordConfirm checks that a new password is repeated in the
+// <field>Confirm property of data, as PocketBase requires
+func validatePasswordConfirm(errs fieldErrors, field models.Field, data map[string]interface{}) bool {
+	if field.Type != models.FieldTypePassword {
+		return true
+	}
+	if confirm, _ := data[field.Name+"Confirm"].(string); confirm != data[field.Name] {
+		errs.Add(field.Name+"Confirm", codeValuesMismatch, "Values don't match.")
+		return false
+	}
+	return true
+}
+
+// checkOldPassword requires records changing their own password to send the
+// current one in oldPassword. Admins can reset passwords without it.
+func (r *recordRequest) checkOldPassword(q querier, collection *models.Collection, id string, data map[string]interface{}) error {
+	field, ok := passwordField(collection)
+	if r.admin || r.system || !ok {
+		return nil
+	}
+	if password, _ := data[field.Name].(string); password == "" {
+		return nil
+	}
+
+	var hash sql.NullString
+	query := fmt.Sprintf("SELECT [%s] FROM [%s] WHERE [id] = ?", field.ColumnName(), collection.Table)
+	if err := q.QueryRow(query, id).Scan(&hash); err != nil {
+		return err
+	}
+	oldPassword, _ := data["oldPassword"].(string)
+	if hash.Valid && bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(oldPassword)) != nil {
+		return newFieldError("oldPassword", codeInvalidValue, "Missing or invalid old password.")
+	}
+	return nil
+}
+
+// privilegedAuthFields are the fields of auth collections that only admins
+// can set, so that records cannot e.g. verify themselves
+var privilegedAuthFields = []string{"verified"}
+
+// checkPrivilegedFields rejects changes to the privileged auth fields of
+// records created or updated by non-admins. current is nil on create, where
+// the fields must keep their zero value.
+func (r *recordRequest) checkPrivilegedFields(collection *models.Collection, current models.Record, data map[string]interface{}) error {
+	if r.admin || r.system || !collection.IsAuth() {
+		return nil
+	}
+	for _, name := range privilegedAuthFields {
+		field, ok := collection.Field(name)
+		raw, present := data[name]
+		if !ok || !present {
+			continue
+		}
+		value, err := fieldValue(field, raw)
+		if err != nil {
+			return err
+		}
+		var was interface{} = false
+		if current != nil {
+			was = current[name]
+		}
+		if value != was {
+			return newFieldError(name, codeInvalidValue, "Only admins can change this field.")
+		}
+	}
+	return nil
+}
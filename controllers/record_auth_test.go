@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialChangesRevokeAuthTokens(t *testing.T) {
+	openTestDB(t)
+	t.Setenv("ACCESS_SECRET", "test-access-secret")
+	r := newTestRouter()
+	pb := new(PocketBaseController)
+	r.POST("/api/collections/:collection/records", pb.CreateRecord)
+	r.PATCH("/api/collections/:collection/records/:id", pb.UpdateRecord)
+	r.POST("/api/collections/:collection/auth-with-password", RecordAuthController{}.AuthWithPassword)
+	r.POST("/api/collections/:collection/auth-refresh", RecordAuthController{}.AuthRefresh)
+
+	var user map[string]interface{}
+	status := serveJSON(t, r, http.MethodPost, "/api/collections/users/records", "", map[string]interface{}{
+		"email": "jane@example.com", "username": "jane", "password": "password123", "passwordConfirm": "password123",
+	}, &user)
+	require.Equal(t, http.StatusOK, status, user)
+	path := "/api/collections/users/records/" + user["id"].(string)
+
+	signIn := func(password string) string {
+		var auth models.PBAuthResponse
+		status := serveJSON(t, r, http.MethodPost, "/api/collections/users/auth-with-password", "",
+			map[string]interface{}{"identity": "jane", "password": password}, &auth)
+		require.Equal(t, http.StatusOK, status)
+		return auth.Token
+	}
+	refresh := func(token string) int {
+		return serveJSON(t, r, http.MethodPost, "/api/collections/users/auth-refresh", token, nil, nil)
+	}
+
+	token := signIn("password123")
+	assert.Equal(t, http.StatusOK, refresh(token))
+
+	status = serveJSON(t, r, http.MethodPatch, path, token, map[string]interface{}{"name": "Jane"}, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, refresh(token), "other changes keep the token valid")
+
+	status = serveJSON(t, r, http.MethodPatch, path, token, map[string]interface{}{
+		"password": "password456", "passwordConfirm": "password456", "oldPassword": "password123",
+	}, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusUnauthorized, refresh(token))
+
+	token = signIn("password456")
+	status = serveJSON(t, r, http.MethodPatch, path, token, map[string]interface{}{"email": "JANE@example.com"}, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, refresh(token), "the same email keeps the token valid")
+
+	status = serveJSON(t, r, http.MethodPatch, path, token, map[string]interface{}{"email": "jane.doe@example.com"}, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusUnauthorized, refresh(token))
+}
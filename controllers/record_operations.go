@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Record operations shared by the record handlers and batch requests. Each
+// write runs in a transaction and applies, in order: the API rule, the
+// before hook, the write itself and, once committed, the after hook and the
+// realtime events.
+
+// recordRequest describes the API request a record operation runs for
+type recordRequest struct {
+	auth      models.Record // authenticated record, nil for guests
+	admin     bool
+	system    bool   // internal job rather than an API request
+	requestID string // X-Request-Id of the request, for the audit log
+	method    string
+	query     url.Values
+	headers   http.Header
+	body      map[string]interface{}
+	files     map[string][]*multipart.FileHeader // uploaded files by form key
+
+	// afterCommit holds the side effects of the operations run for the
+	// request, performed by committed once their transaction commits, and
+	// afterRollback undoes those that cannot wait, such as stored uploads
+	afterCommit   []func()
+	afterRollback []func()
+}
+
+func newRecordRequest(c *gin.Context, body map[string]interface{}) *recordRequest {
+	return &recordRequest{
+		auth:      requestAuthRecord(c),
+		admin:     IsAdmin(c),
+		requestID: c.GetString(requestIDKey),
+		method:    c.Request.Method,
+		query:     c.Request.URL.Query(),
+		headers:   c.Request.Header,
+		body:      body,
+	}
+}
+
+// committed performs the side effects queued by the operations of r. It must
+// only be called after their transaction committed.
+func (r *recordRequest) committed() {
+	for _, fn := range r.afterCommit {
+		fn()
+	}
+	r.afterCommit = nil
+	r.afterRollback = nil
+}
+
+// rolledBack undoes the side effects of the operations of r, in reverse
+// order. It must be called when their transaction failed.
+func (r *recordRequest) rolledBack() {
+	for i := len(r.afterRollback) - 1; i >= 0; i-- {
+		r.afterRollback[i]()
+	}
+	r.afterCommit = nil
+	r.afterRollback = nil
+}
+
+// event returns the hook event of an operation of r
+func (r *recordRequest) event(tx *sql.Tx, collection *models.Collection, record models.Record, data map[string]interface{}) *models.RecordEvent {
+	return &models.RecordEvent{Collection: collection, Record: record, Data: data, Tx: tx, Auth: r.auth, Admin: r.admin}
+}
+
+// before runs a before hook, converting its error into an API error
+func (r *recordRequest) before(hook *models.RecordHook, e *models.RecordEvent) error {
+	err := hook.Trigger(e)
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *apiError
+	var fieldErrs fieldErrors
+	if errors.As(err, &apiErr) || errors.As(err, &fieldErrs) {
+		return err
+	}
+	return newAPIError(http.StatusBadRequest, err.Error())
+}
+
+// after queues an after hook and the realtime event of a committed write
+func (r *recordRequest) after(hook *models.RecordHook, action string, collection *models.Collection, record models.Record, realtime func()) {
+	e := r.event(nil, collection, record, nil)
+	r.afterCommit = append(r.afterCommit, func() {
+		if err := hook.Trigger(e); err != nil {
+			log.Printf("%s hook of %s/%s failed: %v", action, collection.Name, record.ID(), err)
+		}
+		realtime()
+	})
+}
+
+// view loads a record allowed by the view rule, returning sql.ErrNoRows for
+// records the rule hides
+func (r *recordRequest) view(q querier, collection *models.Collection, id string) (models.Record, error) {
+	matches, err := r.matchesRule(q, collection, collection.ViewRule, id)
+	if err != nil {
+		return nil, err
+	}
+	if !matches {
+		return nil, sql.ErrNoRows
+	}
+	return findRecord(q, collection, id)
+}
+
+// create inserts a record and checks it against the create rule. tx is
+// rolled back by the caller on error, so that records rejected by the rule
+// are not kept.
+func (r *recordRequest) create(tx *sql.Tx, collection *models.Collection, data map[string]interface{}) (models.Record, error) {
+	_, _, allowed, err := r.ruleCondition(collection, collection.CreateRule)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errAdminOnly
+	}
+	if err := r.checkPrivilegedFields(collection, nil, data); err != nil {
+		return nil, err
+	}
+
+	files, err := r.prepareFiles(collection, nil, data)
+	if err != nil {
+		return nil, err
+	}
+
+	e := r.event(tx, collection, nil, data)
+	if err := r.before(models.OnRecordBeforeCreate, e); err != nil {
+		return nil, err
+	}
+
+	record, err := insertRecord(tx, collection, e.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := r.matchesRule(tx, collection, collection.CreateRule, record.ID())
+	if err != nil {
+		return nil, err
+	}
+	if !matches {
+		return nil, newAPIError(http.StatusBadRequest, "Failed to create record.")
+	}
+	if err := r.storeFiles(collection, record.ID(), files); err != nil {
+		return nil, err
+	}
+	if err := r.audit(tx, models.AuditOperationCreate, collection, record.ID(), nil, record, e.Data); err != nil {
+		return nil, err
+	}
+
+	r.after(models.OnRecordAfterCreate, realtimeActionCreate, collection, record,
+		realtimeClients.recordEvent(realtimeActionCreate, collection, record))
+	return record, nil
+}
+
+// update applies data to a record allowed by the update rule, at the
+// version requested with If-Match
+func (r *recordRequest) update(tx *sql.Tx, collection *models.Collection, id string, data map[string]interface{}) (models.Record, error) {
+	matches, err := r.matchesRule(tx, collection, collection.UpdateRule, id)
+	if err != nil {
+		return nil, err
+	}
+	if !matches {
+		return nil, sql.ErrNoRows
+	}
+
+	current, err := findRecord(tx, collection, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkVersion(collection, current); err != nil {
+		return nil, err
+	}
+	if err := r.checkOldPassword(tx, collection, id, data); err != nil {
+		return nil, err
+	}
+	if err := r.checkPrivilegedFields(collection, current, data); err != nil {
+		return nil, err
+	}
+	files, err := r.prepareFiles(collection, current, data)
+	if err != nil {
+		return nil, err
+	}
+
+	e := r.event(tx, collection, current, data)
+	if err := r.before(models.OnRecordBeforeUpdate, e); err != nil {
+		return nil, err
+	}
+
+	record, err := updateRecord(tx, collection, id, e.Data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.storeFiles(collection, id, files); err != nil {
+		return nil, err
+	}
+	if err := r.audit(tx, models.AuditOperationUpdate, collection, id, current, record, e.Data); err != nil {
+		return nil, err
+	}
+
+	r.after(models.OnRecordAfterUpdate, realtimeActionUpdate, collection, record,
+		realtimeClients.recordEvent(realtimeActionUpdate, collection, record))
+	return record, nil
+}
+
+// delete moves a record allowed by the delete rule to the trash, at the
+// version requested with If-Match
+func (r *recordRequest) delete(tx *sql.Tx, collection *models.Collection, id string) error {
+	matches, err := r.matchesRule(tx, collection, collection.DeleteRule, id)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return sql.ErrNoRows
+	}
+
+	record, err := findRecord(tx, collection, id)
+	if err != nil {
+		return err
+	}
+	if err := r.checkVersion(collection, record); err != nil {
+		return err
+	}
+	return r.remove(tx, collection, record)
+}
+
+// remove moves a record allowed to be deleted to the trash along with the
+// records referencing it through CASCADE foreign keys
+func (r *recordRequest) remove(tx *sql.Tx, collection *models.Collection, record models.Record) error {
+	if err := r.before(models.OnRecordBeforeDelete, r.event(tx, collection, record, nil)); err != nil {
+		return err
+	}
+
+	if err := deleteRecord(tx, collection, record.ID()); err != nil {
+		return err
+	}
+	if err := checkSuperusersLeft(tx, collection); err != nil {
+		return err
+	}
+	r.revokeUserSessions(collection, record.ID())
+	if err := r.deleteReferences(tx, collection, record.ID()); err != nil {
+		return err
+	}
+	if err := r.audit(tx, models.AuditOperationDelete, collection, record.ID(), record, nil, nil); err != nil {
+		return err
+	}
+
+	r.after(models.OnRecordAfterDelete, realtimeActionDelete, collection, record,
+		realtimeClients.recordEvent(realtimeActionDelete, collection, record))
+	return nil
+}
+
+// upsert updates the record identified by data["id"] when it exists and
+// creates it otherwise, checking the update or create rule respectively
+func (r *recordRequest) upsert(tx *sql.Tx, collection *models.Collection, data map[string]interface{}) (models.Record, error) {
+	id, _ := data["id"].(string)
+	if id == "" {
+		return r.create(tx, collection, data)
+	}
+	if !recordIDRegex.MatchString(id) {
+		return nil, invalidRecordID()
+	}
+
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%s] WHERE [id] = ?)", collection.Table)
+	if err := tx.QueryRow(query, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return r.create(tx, collection, data)
+	}
+
+	record, err := r.update(tx, collection, id, data)
+	if errors.Is(err, errNoFieldsToUpdate) {
+		return findRecord(tx, collection, id)
+	}
+	return record, err
+}
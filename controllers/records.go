@@ -0,0 +1,571 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/models"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// Generic record persistence driven by the collection registry. Every
+// collection shares the same scanning, validation and SQL generation, so
+// adding a collection only requires registering its schema.
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+var errNoFieldsToUpdate = errors.New("no fields to update")
+
+// recordIDRegex matches the ids clients may choose for new records. Ids also
+// name the directory of the record files, so path separators and dots are
+// not allowed.
+var recordIDRegex = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// invalidRecordID is the validation error of a client id not matching
+// recordIDRegex
+func invalidRecordID() fieldErrors {
+	return newFieldError("id", codeInvalidValue, "Must be 1 to 50 lowercase letters, digits or underscores.")
+}
+
+// dateLayouts are the datetime formats accepted for date fields
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.000Z",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid datetime %q", value)
+}
+
+// nullDate scans datetime columns stored either as native times or as text
+type nullDate struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (d *nullDate) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		d.Valid = false
+	case time.Time:
+		d.Time, d.Valid = v, true
+	case string:
+		t, err := parseDate(v)
+		if err != nil {
+			return err
+		}
+		d.Time, d.Valid = t, true
+	case []byte:
+		return d.Scan(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into a date", value)
+	}
+	return nil
+}
+
+// recordColumns returns the qualified column list selected for a collection
+func recordColumns(collection *models.Collection) string {
+	fields := collection.AllFields()
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = "[" + collection.Table + "].[" + f.ColumnName() + "]"
+	}
+	return strings.Join(columns, ", ")
+}
+
+func scanTarget(field models.Field) interface{} {
+	switch field.Type {
+	case models.FieldTypeNumber:
+		return &sql.NullFloat64{}
+	case models.FieldTypeBool:
+		return &sql.NullBool{}
+	case models.FieldTypeDate:
+		return &nullDate{}
+	default:
+		return &sql.NullString{}
+	}
+}
+
+func scannedValue(field models.Field, target interface{}) interface{} {
+	switch v := target.(type) {
+	case *sql.NullFloat64:
+		return v.Float64
+	case *sql.NullBool:
+		return v.Bool
+	case *nullDate:
+		if !v.Valid {
+			return ""
+		}
+		return v.Time
+	case *sql.NullString:
+		if field.Type == models.FieldTypeFile {
+			names := storedFileNames(v.String)
+			if field.IsMultiple() {
+				return names
+			}
+			if len(names) == 0 {
+				return ""
+			}
+			return names[len(names)-1]
+		}
+		if field.Type == models.FieldTypeJSON {
+			if !v.Valid || v.String == "" {
+				return nil
+			}
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(v.String), &decoded); err != nil {
+				return v.String
+			}
+			return decoded
+		}
+		return v.String
+	}
+	return nil
+}
+
+// scanRecord reads one row selected with recordColumns into a Record
+func scanRecord(collection *models.Collection, scan func(dest ...interface{}) error) (models.Record, error) {
+	fields := collection.AllFields()
+	targets := make([]interface{}, len(fields))
+	for i, f := range fields {
+		targets[i] = scanTarget(f)
+	}
+
+	if err := scan(targets...); err != nil {
+		return nil, err
+	}
+
+	record := models.Record{
+		"collectionId":   collection.ID,
+		"collectionName": collection.Name,
+	}
+	for i, f := range fields {
+		// Password hashes never leave the database
+		if f.Type == models.FieldTypePassword {
+			continue
+		}
+		record[f.Name] = scannedValue(f, targets[i])
+	}
+	return record, nil
+}
+
+// liveCondition returns the condition excluding the records of collection
+// that were moved to the trash
+func liveCondition(collection *models.Collection) string {
+	return "[" + collection.Table + "].[deleted] IS NULL"
+}
+
+// findRecord loads a single record by id, returning sql.ErrNoRows when it
+// does not exist or is in the trash
+func findRecord(q querier, collection *models.Collection, id string) (models.Record, error) {
+	query := fmt.Sprintf("SELECT %s FROM [%s] WHERE [%s].[id] = ? AND %s",
+		recordColumns(collection), collection.Table, collection.Table, liveCondition(collection))
+	return scanRecord(collection, q.QueryRow(query, id).Scan)
+}
+
+// queryRecords runs a SELECT over the collection table with the given
+// clauses and returns the scanned records
+func queryRecords(q querier, collection *models.Collection, clauses string, args ...interface{}) ([]models.Record, error) {
+	query := fmt.Sprintf("SELECT %s FROM [%s] %s", recordColumns(collection), collection.Table, clauses)
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.Record{}
+	for rows.Next() {
+		record, err := scanRecord(collection, rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// fieldValue converts a decoded JSON value into the value stored for a field
+func fieldValue(field models.Field, raw interface{}) (interface{}, error) {
+	switch field.Type {
+	case models.FieldTypeText, models.FieldTypeEmail:
+		var text string
+		switch v := raw.(type) {
+		case nil:
+		case string:
+			text = v
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			text = strconv.FormatBool(v)
+		default:
+			return nil, newFieldError(field.Name, codeInvalidValue, "Must be a string.")
+		}
+		if field.Type == models.FieldTypeEmail && text != "" {
+			if _, err := mail.ParseAddress(text); err != nil {
+				return nil, newFieldError(field.Name, codeInvalidEmail, "Must be a valid email address.")
+			}
+		}
+		return text, nil
+
+	case models.FieldTypeNumber:
+		switch v := raw.(type) {
+		case nil:
+			return nil, nil
+		case float64:
+			return v, nil
+		case string:
+			if v == "" {
+				return nil, nil
+			}
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, newFieldError(field.Name, codeInvalidNumber, "Must be a valid number.")
+			}
+			return n, nil
+		}
+		return nil, newFieldError(field.Name, codeInvalidNumber, "Must be a valid number.")
+
+	case models.FieldTypeBool:
+		switch v := raw.(type) {
+		case nil:
+			return false, nil
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+		return nil, newFieldError(field.Name, codeInvalidBool, "Must be a boolean.")
+
+	case models.FieldTypeDate:
+		switch v := raw.(type) {
+		case nil:
+			return nil, nil
+		case string:
+			if v == "" {
+				return nil, nil
+			}
+			t, err := parseDate(v)
+			if err != nil {
+				return nil, newFieldError(field.Name, codeInvalidDate, "Must be a valid datetime.")
+			}
+			return t.UTC(), nil
+		}
+		return nil, newFieldError(field.Name, codeInvalidDate, "Must be a valid datetime.")
+
+	case models.FieldTypeJSON:
+		encoded, err := json.Marshal(raw)
+		if err != nil {
+			return nil, newFieldError(field.Name, codeInvalidJSON, "Must be valid JSON.")
+		}
+		return string(encoded), nil
+
+	case models.FieldTypeFile:
+		names, ok := fileNamesValue(raw)
+		if !ok {
+			return nil, newFieldError(field.Name, codeInvalidValue, "Must be a file name or a list of file names.")
+		}
+		if len(names) == 0 {
+			return nil, nil
+		}
+		if !field.IsMultiple() {
+			if len(names) > 1 {
+				return nil, newFieldError(field.Name, codeTooManyValues, "Must contain at most 1 file.")
+			}
+			return names[0], nil
+		}
+		if len(names) > field.MaxSelect {
+			return nil, newFieldError(field.Name, codeTooManyValues, fmt.Sprintf("Must contain at most %d files.", field.MaxSelect))
+		}
+		encoded, err := json.Marshal(names)
+		if err != nil {
+			return nil, err
+		}
+		return string(encoded), nil
+
+	case models.FieldTypeRelation:
+		switch v := raw.(type) {
+		case nil:
+			return nil, nil
+		case string:
+			if v == "" {
+				return nil, nil
+			}
+			return v, nil
+		}
+		return nil, newFieldError(field.Name, codeInvalidValue, "Must be a record id.")
+
+	case models.FieldTypePassword:
+		return passwordValue(field, raw)
+	}
+
+	return nil, newFieldError(field.Name, codeInvalidValue, "Unsupported field type.")
+}
+
+// validateFieldValue converts raw for field, recording conversion and
+// required errors in errs
+func validateFieldValue(errs fieldErrors, field models.Field, raw interface{}) (interface{}, bool) {
+	value, err := fieldValue(field, raw)
+	if err != nil {
+		var fieldErrs fieldErrors
+		if !errors.As(err, &fieldErrs) {
+			fieldErrs = newFieldError(field.Name, codeInvalidValue, err.Error())
+		}
+		for name, fe := range fieldErrs {
+			errs[name] = fe
+		}
+		return nil, false
+	}
+	if field.Required && isEmptyValue(value) {
+		errs.Add(field.Name, codeRequired, "Cannot be blank.")
+		return nil, false
+	}
+	return value, true
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	}
+	return false
+}
+
+// insertRecord validates data against the collection schema, inserts it and
+// returns the stored record
+func insertRecord(q querier, collection *models.Collection, data map[string]interface{}) (models.Record, error) {
+	id := generateID()
+	if customID, ok := data["id"].(string); ok && customID != "" {
+		if !recordIDRegex.MatchString(customID) {
+			return nil, invalidRecordID()
+		}
+		id = customID
+	}
+	now := time.Now().UTC()
+
+	columns := []string{"[id]", "[created]", "[updated]", "[collection_id]", "[collection_name]"}
+	values := []interface{}{id, now, now, collection.ID, collection.Name}
+	if collection.IsAuth() {
+		columns = append(columns, "["+models.TokenKeyColumn+"]")
+		values = append(values, models.NewTokenKey())
+	}
+
+	errs := fieldErrors{}
+	for _, field := range collection.Fields {
+		raw, ok := data[field.Name]
+		if !ok {
+			if field.Required {
+				errs.Add(field.Name, codeRequired, "Cannot be blank.")
+			}
+			continue
+		}
+
+		value, ok := validateFieldValue(errs, field, raw)
+		if !ok {
+			continue
+		}
+		ok, err := validateRelation(q, errs, field, value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || !validatePasswordConfirm(errs, field, data) {
+			continue
+		}
+
+		columns = append(columns, "["+field.ColumnName()+"]")
+		values = append(values, value)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	query := fmt.Sprintf("INSERT INTO [%s] (%s) VALUES (%s)", collection.Table, strings.Join(columns, ", "), placeholders)
+	if _, err := q.Exec(query, values...); err != nil {
+		return nil, constraintError(q, collection, data, err)
+	}
+
+	return findRecord(q, collection, id)
+}
+
+// updateRecord applies the known fields in data to an existing record and
+// returns the stored record, returning sql.ErrNoRows when it does not exist
+// or is in the trash
+func updateRecord(q querier, collection *models.Collection, id string, data map[string]interface{}) (models.Record, error) {
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%s] WHERE [id] = ? AND %s)", collection.Table, liveCondition(collection))
+	if err := q.QueryRow(query, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	var setParts []string
+	var args []interface{}
+
+	errs := fieldErrors{}
+	for _, field := range collection.Fields {
+		raw, ok := data[field.Name]
+		if !ok {
+			continue
+		}
+
+		value, ok := validateFieldValue(errs, field, raw)
+		if !ok {
+			continue
+		}
+		ok, err := validateRelation(q, errs, field, value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || !validatePasswordConfirm(errs, field, data) {
+			continue
+		}
+
+		setParts = append(setParts, "["+field.ColumnName()+"] = ?")
+		args = append(args, value)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	if len(setParts) == 0 {
+		return nil, errNoFieldsToUpdate
+	}
+
+	if set, tokenKeyArgs := tokenKeyUpdate(collection, data); set != "" {
+		setParts = append(setParts, set)
+		args = append(args, tokenKeyArgs...)
+	}
+
+	// Always update the updated timestamp
+	setParts = append(setParts, "[updated] = ?")
+	args = append(args, time.Now().UTC(), id)
+
+	query = fmt.Sprintf("UPDATE [%s] SET %s WHERE [id] = ? AND %s", collection.Table, strings.Join(setParts, ", "), liveCondition(collection))
+	if _, err := q.Exec(query, args...); err != nil {
+		return nil, constraintError(q, collection, data, err)
+	}
+
+	return findRecord(q, collection, id)
+}
+
+// deleteRecord moves a record to the trash, returning sql.ErrNoRows when it
+// does not exist or is already there. Trashed records keep their relations
+// and files until they are purged.
+func deleteRecord(q querier, collection *models.Collection, id string) error {
+	now := time.Now().UTC()
+	query := fmt.Sprintf("UPDATE [%s] SET [deleted] = ?, [updated] = ? WHERE [id] = ? AND [deleted] IS NULL", collection.Table)
+	result, err := q.Exec(query, now, now, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// constraintMessageRegex extracts the detail of a SQLite constraint error,
+// e.g. "categories.slug" from "UNIQUE constraint failed: categories.slug"
+var constraintMessageRegex = regexp.MustCompile(`constraint failed: (.+)$`)
+
+var identifierWordRegex = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// constraintError converts a SQLite constraint violation raised while
+// writing a record into field errors. data holds the submitted values and is
+// used to find the relation fields behind a FOREIGN KEY failure.
+func constraintError(q querier, collection *models.Collection, data map[string]interface{}, err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+		return err
+	}
+
+	detail := ""
+	if m := constraintMessageRegex.FindStringSubmatch(sqliteErr.Error()); m != nil {
+		detail = m[1]
+	}
+
+	// RESTRICT foreign keys are enforced by a trigger and fail as such
+	code := sqliteErr.ExtendedCode
+	if code == sqlite3.ErrConstraintTrigger && strings.HasPrefix(sqliteErr.Error(), "FOREIGN KEY") {
+		code = sqlite3.ErrConstraintForeignKey
+	}
+
+	errs := fieldErrors{}
+	switch code {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		for _, column := range strings.Split(detail, ",") {
+			column = strings.TrimSpace(column)
+			column = column[strings.LastIndex(column, ".")+1:]
+			if field, ok := fieldByColumn(collection, column); ok {
+				errs.Add(field.Name, codeNotUnique, "Value must be unique.")
+			}
+		}
+
+	case sqlite3.ErrConstraintNotNull:
+		column := detail[strings.LastIndex(detail, ".")+1:]
+		if field, ok := fieldByColumn(collection, column); ok {
+			errs.Add(field.Name, codeRequired, "Cannot be blank.")
+		}
+
+	case sqlite3.ErrConstraintCheck:
+		for _, word := range identifierWordRegex.FindAllString(detail, -1) {
+			if field, ok := fieldByColumn(collection, word); ok {
+				errs.Add(field.Name, codeInvalidValue, "Invalid value.")
+			}
+		}
+
+	case sqlite3.ErrConstraintForeignKey:
+		if data == nil {
+			return errRecordReferenced
+		}
+		for _, field := range collection.Fields {
+			if _, err := validateRelation(q, errs, field, data[field.Name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return newAPIError(http.StatusBadRequest, "The record violates a database constraint.")
+	}
+	return errs
+}
+
+// fieldByColumn finds the field stored in column
+func fieldByColumn(collection *models.Collection, column string) (models.Field, bool) {
+	for _, f := range collection.AllFields() {
+		if strings.EqualFold(f.ColumnName(), column) {
+			return f, true
+		}
+	}
+	return models.Field{}, false
+}
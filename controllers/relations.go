@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/models"
+)
+
+// Referential integrity.
+//
+// SQLite enforces the foreign keys of the collection tables on every
+// connection, but records are only removed from their tables when they are
+// purged from the trash. Deleting a record therefore applies the ON DELETE
+// action of the foreign keys pointing to it to the live records itself:
+// RESTRICT and NO ACTION keys block the delete, CASCADE keys move the
+// referencing records to the trash too, and SET NULL keys are left to the
+// purge so that restoring the record keeps its relations.
+
+var errRecordReferenced = newAPIError(http.StatusBadRequest, "The record is referenced by other records and cannot be deleted.")
+
+// reference is a foreign key of a collection table
+type reference struct {
+	collection *models.Collection
+	column     string
+	onDelete   string
+}
+
+// restricts reports whether the foreign key blocks deleting the records it
+// points to
+func (ref reference) restricts() bool {
+	return ref.onDelete == "RESTRICT" || ref.onDelete == "NO ACTION"
+}
+
+// references returns the foreign keys of the collection tables pointing to
+// table
+func references(q querier, table string) ([]reference, error) {
+	var refs []reference
+	for _, collection := range models.Collections() {
+		rows, err := q.Query(`SELECT "table", "from", on_delete FROM pragma_foreign_key_list(?)`, collection.Table)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var target string
+			ref := reference{collection: collection}
+			if err := rows.Scan(&target, &ref.column, &ref.onDelete); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if strings.EqualFold(target, table) {
+				ref.onDelete = strings.ToUpper(ref.onDelete)
+				refs = append(refs, ref)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+// deleteReferences applies the foreign keys pointing to a record that was
+// just moved to the trash to the live records referencing it
+func (r *recordRequest) deleteReferences(tx *sql.Tx, collection *models.Collection, id string) error {
+	refs, err := references(tx, collection.Table)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		if !ref.restricts() {
+			continue
+		}
+		var referenced bool
+		query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%s] WHERE [%s] = ? AND %s)",
+			ref.collection.Table, ref.column, liveCondition(ref.collection))
+		if err := tx.QueryRow(query, id).Scan(&referenced); err != nil {
+			return err
+		}
+		if referenced {
+			return errRecordReferenced
+		}
+	}
+
+	for _, ref := range refs {
+		if ref.onDelete != "CASCADE" {
+			continue
+		}
+		where := fmt.Sprintf("WHERE [%s].[%s] = ? AND %s", ref.collection.Table, ref.column, liveCondition(ref.collection))
+		records, err := queryRecords(tx, ref.collection, where, id)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := r.remove(tx, ref.collection, record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// purgeReferences purges the trashed records referencing a record about to
+// be purged through CASCADE foreign keys, whose rows SQLite would otherwise
+// delete without removing their files or logging them. Live records
+// referencing it through such keys block the purge: they are deleted
+// through the trash first.
+func (r *recordRequest) purgeReferences(tx *sql.Tx, collection *models.Collection, id string) error {
+	refs, err := references(tx, collection.Table)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		if ref.onDelete != "CASCADE" {
+			continue
+		}
+		var referenced bool
+		query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%s] WHERE [%s] = ? AND %s)",
+			ref.collection.Table, ref.column, liveCondition(ref.collection))
+		if err := tx.QueryRow(query, id).Scan(&referenced); err != nil {
+			return err
+		}
+		if referenced {
+			return errRecordReferenced
+		}
+	}
+
+	for _, ref := range refs {
+		if ref.onDelete != "CASCADE" {
+			continue
+		}
+		query := fmt.Sprintf("SELECT [id] FROM [%s] WHERE [%s] = ?", ref.collection.Table, ref.column)
+		rows, err := tx.Query(query, id)
+		if err != nil {
+			return err
+		}
+		var ids []string
+		for rows.Next() {
+			var refID string
+			if err := rows.Scan(&refID); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, refID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, refID := range ids {
+			if err := r.purge(tx, ref.collection, refID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// relationExists reports whether id is a record of the collection a relation
+// field points to that is not in the trash
+func relationExists(q querier, field models.Field, id string) (bool, error) {
+	target, ok := models.FindCollection(field.Relation)
+	if !ok {
+		return false, nil
+	}
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%s] WHERE [%[1]s].[id] = ? AND %s)", target.Table, liveCondition(target))
+	err := q.QueryRow(query, id).Scan(&exists)
+	return exists, err
+}
+
+// validateRelation checks that the value of a relation field points to an
+// existing record, adding a field error otherwise
+func validateRelation(q querier, errs fieldErrors, field models.Field, value interface{}) (bool, error) {
+	id, ok := value.(string)
+	if field.Type != models.FieldTypeRelation || !ok || id == "" {
+		return true, nil
+	}
+	exists, err := relationExists(q, field, id)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		errs.Add(field.Name, codeMissingRelation, "Failed to find all relation records with the provided ids.")
+	}
+	return exists, nil
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by LoadRequestAuth
+const (
+	authRecordKey = "authRecord"
+	adminKey      = "admin"
+)
+
+// errUnknownAuthCollection is returned for record auth tokens of collections
+// that do not exist or are not auth collections
+var errUnknownAuthCollection = errors.New("unknown auth collection")
+
+// LoadRequestAuth identifies the caller from the Authorization header, which
+// holds either the ADMIN_TOKEN or a record auth token, optionally prefixed
+// with "Bearer ". Records of the _superusers collection are admins and
+// bypass the API rules. Invalid or expired record tokens, and those issued
+// before the token key of their record was renewed, are ignored and the
+// request continues as a guest, as in PocketBase.
+func LoadRequestAuth(c *gin.Context) {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if token == "" {
+		return
+	}
+
+	if isAdminToken(token) {
+		c.Set(adminKey, true)
+		return
+	}
+
+	conn := db.GetDB().Db
+	var collection *models.Collection
+	claims, err := models.ParseRecordAuthToken(token, func(claims *models.RecordAuthClaims) (string, error) {
+		var ok bool
+		collection, ok = models.FindCollectionByID(claims.CollectionID)
+		if !ok || !collection.IsAuth() {
+			return "", errUnknownAuthCollection
+		}
+		return recordTokenKey(conn, collection, claims.ID)
+	})
+	if err != nil {
+		return
+	}
+	record, err := findRecord(conn, collection, claims.ID)
+	if err != nil {
+		return
+	}
+	c.Set(authRecordKey, record)
+	if collection.Name == models.SuperusersCollectionName {
+		c.Set(adminKey, true)
+	}
+}
+
+// IsAdmin reports whether the request was authenticated as a superuser or
+// with the ADMIN_TOKEN
+func IsAdmin(c *gin.Context) bool {
+	return c.GetBool(adminKey)
+}
+
+// requestAuthRecord returns the authenticated record, or nil for guests
+func requestAuthRecord(c *gin.Context) models.Record {
+	record, _ := c.Get(authRecordKey)
+	auth, _ := record.(models.Record)
+	return auth
+}
+
+// isAdminToken compares token with the ADMIN_TOKEN in constant time. Admin
+// access is disabled when ADMIN_TOKEN is not set.
+func isAdminToken(token string) bool {
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	return adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/models"
+)
+
+// PocketBase API rules.
+//
+// Every collection has a rule per action. A nil rule restricts the action to
+// admins, an empty rule allows everyone and any other rule is a filter the
+// affected records must match. Rules may reference the current request
+// through @request.* identifiers, e.g. `user = @request.auth.id`. The
+// :isset modifier of @request.body.* identifiers tells whether the request
+// submits the field, e.g. `@request.body.user:isset = false`.
+
+var errAdminOnly = newAPIError(http.StatusForbidden, "Only admins can perform this action.")
+
+// resolver resolves the fields of collection and the @request.* identifiers
+// of r, which are bound as arguments, for the API rules
+func (r *recordRequest) resolver(collection *models.Collection) filterFieldResolver {
+	return r.fieldResolver(collection, false)
+}
+
+// filterResolver is the resolver of the filters sent by clients, which can
+// only follow relations into collections hidden by their list rule as admins
+func (r *recordRequest) filterResolver(collection *models.Collection) filterFieldResolver {
+	return r.fieldResolver(collection, !r.admin)
+}
+
+func (r *recordRequest) fieldResolver(collection *models.Collection, guarded bool) filterFieldResolver {
+	fields := collectionFilterResolver(collection, guarded)
+
+	return func(name string) (string, []interface{}, error) {
+		if !strings.HasPrefix(name, "@request.") {
+			return fields(name)
+		}
+		value, err := r.requestValue(strings.TrimPrefix(name, "@request."))
+		if err != nil {
+			return "", nil, err
+		}
+		return "?", []interface{}{value}, nil
+	}
+}
+
+// requestValue returns the value of a @request.* identifier. Missing values,
+// such as the auth fields of a guest, resolve to an empty string.
+func (r *recordRequest) requestValue(name string) (interface{}, error) {
+	section, key, _ := strings.Cut(name, ".")
+	switch {
+	case section == "method" && key == "":
+		return r.method, nil
+	case section == "auth" && key != "":
+		if r.auth == nil {
+			return "", nil
+		}
+		return filterValue(r.auth[key]), nil
+	case section == "body" && strings.HasSuffix(key, ":isset"):
+		_, isset := r.body[strings.TrimSuffix(key, ":isset")]
+		return isset, nil
+	case section == "body" && key != "":
+		return filterValue(r.body[key]), nil
+	case section == "query" && key != "":
+		return r.query.Get(key), nil
+	case section == "headers" && key != "":
+		return r.headers.Get(strings.ReplaceAll(key, "_", "-")), nil
+	}
+	return nil, fmt.Errorf("unknown identifier %q", "@request."+name)
+}
+
+// filterValue converts a record or body value into a filter argument
+func filterValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string, float64, bool:
+		return v
+	case time.Time:
+		return v.UTC().Format(filterDateFormat)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// ruleCondition compiles rule into a SQL condition on the records of
+// collection. allowed is false when the rule restricts the action to admins;
+// an empty condition allows every record.
+func (r *recordRequest) ruleCondition(collection *models.Collection, rule *string) (condition string, args []interface{}, allowed bool, err error) {
+	if r.admin {
+		return "", nil, true, nil
+	}
+	if rule == nil {
+		return "", nil, false, nil
+	}
+	if strings.TrimSpace(*rule) == "" {
+		return "", nil, true, nil
+	}
+
+	expr, args, err := filterToSQL(*rule, r.resolver(collection))
+	if err != nil {
+		return "", nil, false, fmt.Errorf("invalid API rule of %s: %w", collection.Name, err)
+	}
+	return "(" + expr + ")", args, true, nil
+}
+
+// listCondition returns the condition limiting a list to the records the
+// list rule allows
+func (r *recordRequest) listCondition(collection *models.Collection) (string, []interface{}, error) {
+	condition, args, allowed, err := r.ruleCondition(collection, collection.ListRule)
+	if err != nil {
+		return "", nil, err
+	}
+	if !allowed {
+		return "", nil, errAdminOnly
+	}
+	return condition, args, nil
+}
+
+// matchesRule reports whether the record id exists and satisfies rule,
+// returning errAdminOnly when the rule is admin-only
+func (r *recordRequest) matchesRule(q querier, collection *models.Collection, rule *string, id string) (bool, error) {
+	condition, args, allowed, err := r.ruleCondition(collection, rule)
+	if err != nil {
+		return false, err
+	}
+	if !allowed {
+		return false, errAdminOnly
+	}
+
+	return recordMatches(q, collection, id, condition, args)
+}
+
+// recordMatches reports whether the record id exists outside the trash and
+// satisfies the SQL condition, which may be empty
+func recordMatches(q querier, collection *models.Collection, id, condition string, args []interface{}) (bool, error) {
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%[1]s] WHERE [%[1]s].[id] = ? AND %[2]s", collection.Table, liveCondition(collection))
+	if condition != "" {
+		query += " AND " + condition
+	}
+	var matches bool
+	err := q.QueryRow(query+")", append([]interface{}{id}, args...)...).Scan(&matches)
+	return matches, err
+}
+
+// isPublicRule reports whether rule allows everyone
+func isPublicRule(rule *string) bool {
+	return rule != nil && strings.TrimSpace(*rule) == ""
+}
+
+// validateRule checks that rule compiles for collection
+func validateRule(collection *models.Collection, rule *string) error {
+	if rule == nil || strings.TrimSpace(*rule) == "" {
+		return nil
+	}
+	_, _, err := filterToSQL(*rule, (&recordRequest{}).resolver(collection))
+	return err
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRecordsTestRouter returns a test router serving the record API and the
+// password sign in
+func newRecordsTestRouter() *gin.Engine {
+	r := newTestRouter()
+	pb := new(PocketBaseController)
+	r.GET("/api/collections/:collection/records", pb.ListRecords)
+	r.GET("/api/collections/:collection/records/:id", pb.GetRecord)
+	r.POST("/api/collections/:collection/records", pb.CreateRecord)
+	r.PATCH("/api/collections/:collection/records/:id", pb.UpdateRecord)
+	r.DELETE("/api/collections/:collection/records/:id", pb.DeleteRecord)
+	r.POST("/api/collections/:collection/auth-with-password", RecordAuthController{}.AuthWithPassword)
+	return r
+}
+
+// signUp creates a user through the API and signs it in, returning its id
+// and token
+func signUp(t *testing.T, r http.Handler, username string) (string, string) {
+	t.Helper()
+	t.Setenv("ACCESS_SECRET", "test-access-secret")
+
+	var user map[string]interface{}
+	status := serveJSON(t, r, http.MethodPost, "/api/collections/users/records", "", map[string]interface{}{
+		"email": username + "@example.com", "username": username, "password": "password123", "passwordConfirm": "password123",
+	}, &user)
+	require.Equal(t, http.StatusOK, status, user)
+
+	var auth models.PBAuthResponse
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/users/auth-with-password", "",
+		map[string]interface{}{"identity": username, "password": "password123"}, &auth)
+	require.Equal(t, http.StatusOK, status, auth)
+	return user["id"].(string), auth.Token
+}
+
+func TestRuleCondition(t *testing.T) {
+	categories, ok := models.FindCollection("categories")
+	require.True(t, ok)
+	guest, admin := &recordRequest{}, &recordRequest{admin: true}
+
+	_, _, allowed, err := guest.ruleCondition(categories, nil)
+	require.NoError(t, err)
+	assert.False(t, allowed, "a nil rule is admin only")
+
+	condition, _, allowed, err := guest.ruleCondition(categories, models.Rule(""))
+	require.NoError(t, err)
+	assert.True(t, allowed, "an empty rule allows everyone")
+	assert.Empty(t, condition)
+
+	condition, _, allowed, err = admin.ruleCondition(categories, nil)
+	require.NoError(t, err)
+	assert.True(t, allowed, "admins ignore the rules")
+	assert.Empty(t, condition)
+
+	condition, args, allowed, err := guest.ruleCondition(categories, models.Rule("slug = @request.auth.id"))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, "([categories].[slug] IS ?)", condition)
+	assert.Equal(t, []interface{}{""}, args, "guests have an empty auth id")
+}
+
+func TestNilRulesAreAdminOnly(t *testing.T) {
+	openTestDB(t)
+	r := newRecordsTestRouter()
+
+	body := map[string]interface{}{"name": "Decks", "slug": "decks"}
+	status := serveJSON(t, r, http.MethodPost, "/api/collections/categories/records", "", body, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/categories/records", testAdminToken, body, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	var list models.PBListResponse
+	status = serveJSON(t, r, http.MethodGet, "/api/collections/categories/records", "", nil, &list)
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, list.Items, "the empty list rule is public")
+
+	status = serveJSON(t, r, http.MethodGet, "/api/collections/_superusers/records", "", nil, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestRulesFilterRecords(t *testing.T) {
+	conn := openTestDB(t)
+	r := newRecordsTestRouter()
+	janeID, jane := signUp(t, r, "jane")
+	johnID, john := signUp(t, r, "john")
+
+	var cart map[string]interface{}
+	status := serveJSON(t, r, http.MethodPost, "/api/collections/carts/records", jane, map[string]interface{}{"user": janeID}, &cart)
+	require.Equal(t, http.StatusOK, status, cart)
+	path := "/api/collections/carts/records/" + cart["id"].(string)
+
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/carts/records", john, map[string]interface{}{"user": janeID}, nil)
+	assert.Equal(t, http.StatusBadRequest, status, "the create rule checks the new record")
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/carts/records", "", map[string]interface{}{"user": janeID}, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	var count int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM carts WHERE user = ?", janeID).Scan(&count))
+	assert.Equal(t, 1, count, "rejected records are not kept")
+
+	for token, want := range map[string]int{jane: 1, john: 0, "": 0} {
+		var list struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		status := serveJSON(t, r, http.MethodGet, "/api/collections/carts/records", token, nil, &list)
+		require.Equal(t, http.StatusOK, status)
+		assert.Len(t, list.Items, want)
+	}
+	assert.Equal(t, http.StatusOK, serveJSON(t, r, http.MethodGet, path, jane, nil, nil))
+	assert.Equal(t, http.StatusNotFound, serveJSON(t, r, http.MethodGet, path, john, nil, nil))
+
+	status = serveJSON(t, r, http.MethodPatch, path, jane, map[string]interface{}{"user": johnID}, nil)
+	assert.Equal(t, http.StatusNotFound, status, "owners cannot hand their records over")
+	status = serveJSON(t, r, http.MethodDelete, path, john, nil, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	status = serveJSON(t, r, http.MethodDelete, path, jane, nil, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+}
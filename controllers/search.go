@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSearchTerms limits the number of terms of a search query
+const maxSearchTerms = 10
+
+// searchWeights are the bm25 weights of the search table columns: the
+// unindexed product id followed by models.ProductsSearchColumns
+var searchWeights = []float64{0, 10, 1, 2, 2}
+
+// Snippets mark matches with control characters, replaced with <mark> tags
+// once the snippet has been HTML-escaped
+const (
+	snippetStart    = "\x02"
+	snippetEnd      = "\x03"
+	snippetEllipsis = "…"
+)
+
+// SearchController handles full-text search
+type SearchController struct{}
+
+// searchHit is a matching product with its highlighted name and description
+type searchHit struct {
+	rowid       int64
+	id          string
+	name        string
+	description string
+}
+
+// Products godoc
+// @Summary Search products
+// @Description Full-text search of products by name, description, category and store name, ranked by relevance (bm25). Every term must match the start of a word, ignoring case and diacritics. The list rule and default filter of products apply. Items carry a highlight object with the name and a description snippet, HTML-escaped with matches wrapped in <mark> tags.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search terms"
+// @Param page query int false "Page number" default(1)
+// @Param perPage query int false "Records per page" default(30)
+// @Param skipTotal query bool false "Skip counting, totalItems and totalPages are -1"
+// @Param filter query string false "Filter query"
+// @Param expand query string false "Expand relations"
+// @Param fields query string false "Fields to return"
+// @Success 200 {object} models.PBListResponse
+// @Router /api/search/products [get]
+func (ctl SearchController) Products(c *gin.Context) {
+	collection, ok := models.FindCollection(models.ProductsSearchCollection)
+	if !ok || !models.ProductsSearchEnabled() {
+		respondError(c, newAPIError(http.StatusNotFound, "Product search is not available."), "")
+		return
+	}
+
+	match, err := searchMatchQuery(c.Query("q"))
+	if err != nil {
+		respondInvalidQuery(c, "q", err)
+		return
+	}
+
+	paging, param, err := parsePaging(c)
+	if err != nil {
+		respondInvalidQuery(c, param, err)
+		return
+	}
+	if paging.cursor != nil {
+		respondInvalidQuery(c, "cursor", errors.New("is not supported by search"))
+		return
+	}
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
+	}
+
+	conn := db.GetDB().Db
+	request := newRecordRequest(c, nil)
+	table := models.ProductsSearchTable
+
+	where := fmt.Sprintf("WHERE [%s] MATCH ? AND %s", table, liveCondition(collection))
+	args := []interface{}{match}
+
+	ruleCondition, ruleArgs, err := request.listCondition(collection)
+	if err != nil {
+		respondError(c, err, "Failed to search records.")
+		return
+	}
+	if ruleCondition != "" {
+		where = andWhere(where, ruleCondition)
+		args = append(args, ruleArgs...)
+	}
+	if filter := listFilter(collection, c.Query("filter")); strings.TrimSpace(filter) != "" {
+		expr, filterArgs, err := filterToSQL(filter, request.filterResolver(collection))
+		if err != nil {
+			respondInvalidQuery(c, "filter", err)
+			return
+		}
+		where = andWhere(where, "("+expr+")")
+		args = append(args, filterArgs...)
+	}
+	from := fmt.Sprintf("FROM [%[1]s] JOIN [%[2]s] ON [%[2]s].rowid = [%[1]s].rowid %[3]s", table, collection.Table, where)
+
+	hits, totalItems, err := searchFTS5(conn, table, from, args, paging)
+	if err != nil {
+		respondError(c, err, "Failed to search records.")
+		return
+	}
+
+	records, err := searchRecords(conn, collection, hits)
+	if err != nil {
+		respondError(c, err, "Failed to search records.")
+		return
+	}
+	records, err = opts.prepare(conn, request, collection, records)
+	if err != nil {
+		respondError(c, err, "Failed to expand records.")
+		return
+	}
+
+	totalPages := -1
+	if totalItems >= 0 {
+		totalPages = (totalItems + paging.perPage - 1) / paging.perPage
+	}
+	c.JSON(http.StatusOK, models.PBListResponse{
+		Page:       paging.page,
+		PerPage:    paging.perPage,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Items:      records,
+	})
+}
+
+// searchMatchQuery converts search terms into a full-text query matching
+// every term as a word prefix. Terms are reduced to their letters and
+// digits, so that the query syntax cannot be injected.
+func searchMatchQuery(q string) (string, error) {
+	words := strings.FieldsFunc(norm.NFC.String(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+	if len(words) == 0 {
+		return "", errors.New("must contain a search term")
+	}
+	if len(words) > maxSearchTerms {
+		return "", fmt.Errorf("must contain at most %d terms", maxSearchTerms)
+	}
+
+	// Terms are lowercased so that they are not read as AND, OR or NOT
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = models.SearchTerm(strings.ToLower(word)) + "*"
+	}
+	return strings.Join(terms, " "), nil
+}
+
+// searchFTS5 returns a page of matches ranked by the FTS5 bm25 function
+func searchFTS5(conn *sql.DB, table, from string, args []interface{}, paging listPaging) ([]searchHit, int, error) {
+	totalItems := -1
+	if !paging.skipTotal {
+		if err := conn.QueryRow("SELECT COUNT(*) "+from, args...).Scan(&totalItems); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	weights := make([]string, len(searchWeights))
+	for i, w := range searchWeights {
+		weights[i] = fmt.Sprint(w)
+	}
+	query := fmt.Sprintf(`SELECT [%[1]s].rowid, [%[1]s].product,
+		snippet([%[1]s], 1, ?, ?, ?, 16), snippet([%[1]s], 2, ?, ?, ?, 32)
+		%[2]s ORDER BY bm25([%[1]s], %[3]s) LIMIT ? OFFSET ?`, table, from, strings.Join(weights, ", "))
+	snippetArgs := []interface{}{snippetStart, snippetEnd, snippetEllipsis, snippetStart, snippetEnd, snippetEllipsis}
+	args = append(append(snippetArgs, args...), paging.perPage, (paging.page-1)*paging.perPage)
+
+	hits, err := scanSearchHits(conn, query, args)
+	return hits, totalItems, err
+}
+
+func scanSearchHits(conn *sql.DB, query string, args []interface{}) ([]searchHit, error) {
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []searchHit{}
+	for rows.Next() {
+		var hit searchHit
+		if err := rows.Scan(&hit.rowid, &hit.id, &hit.name, &hit.description); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// searchRecords loads the records of hits, in order, with their highlight
+func searchRecords(q querier, collection *models.Collection, hits []searchHit) ([]models.Record, error) {
+	if len(hits) == 0 {
+		return []models.Record{}, nil
+	}
+
+	ids := make([]interface{}, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	found, err := queryRecords(q, collection, fmt.Sprintf("WHERE [%s].[id] IN (%s)", collection.Table, placeholders), ids...)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]models.Record{}
+	for _, record := range found {
+		byID[record.ID()] = record
+	}
+
+	records := make([]models.Record, 0, len(hits))
+	for _, hit := range hits {
+		record, ok := byID[hit.id]
+		if !ok {
+			continue
+		}
+		record["highlight"] = map[string]interface{}{
+			"name":        highlightSnippet(hit.name),
+			"description": highlightSnippet(hit.description),
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// highlightSnippet HTML-escapes a snippet and marks its matches
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(models.SearchText(snippet))
+	return strings.NewReplacer(snippetStart, "<mark>", snippetEnd, "</mark>").Replace(escaped)
+}
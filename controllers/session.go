@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionController lists and revokes the sessions of the v1 user
+type SessionController struct{}
+
+var sessionModel = new(models.SessionModel)
+
+// maxUserAgentLength bounds the User-Agent kept with a session
+const maxUserAgentLength = 512
+
+// List Sessions godoc
+// @Summary List sessions
+// @Schemes
+// @Description List the devices the user is logged in on, most recently used first
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 	 200  {object}  models.SessionsResponse
+// @Failure      401  {object}  models.MessageResponse
+// @Router /user/sessions [GET]
+func (ctrl SessionController) List(c *gin.Context) {
+	sessions, err := sessionModel.List(getUserID(c))
+	if err != nil {
+		log.Printf("Failed to list sessions: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong, please try again later"})
+		return
+	}
+
+	current := c.GetString("sessionID")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// Revoke Session godoc
+// @Summary Revoke session
+// @Schemes
+// @Description Log the user out of one of their sessions
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 	 200  {object}  models.MessageResponse
+// @Failure      401  {object}  models.MessageResponse
+// @Failure      404  {object}  models.MessageResponse
+// @Router /user/sessions/{id} [DELETE]
+func (ctrl SessionController) Revoke(c *gin.Context) {
+	err := sessionModel.Revoke(getUserID(c), c.Param("id"))
+	if errors.Is(err, models.ErrSessionNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "Session not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to revoke session: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong, please try again later"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeAll Sessions godoc
+// @Summary Revoke all sessions
+// @Schemes
+// @Description Log the user out of every session, including the current one
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 	 200  {object}  models.MessageResponse
+// @Failure      401  {object}  models.MessageResponse
+// @Router /user/sessions [DELETE]
+func (ctrl SessionController) RevokeAll(c *gin.Context) {
+	revoked, err := sessionModel.RevokeAll(getUserID(c))
+	if err != nil {
+		log.Printf("Failed to revoke sessions: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong, please try again later"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully logged out of all sessions", "revoked": revoked})
+}
+
+// revokeUserSessions schedules the revocation of the v1 sessions of a user
+// being moved to the trash, once the transaction commits
+func (r *recordRequest) revokeUserSessions(collection *models.Collection, id string) {
+	if collection.Name != "users" {
+		return
+	}
+	r.afterCommit = append(r.afterCommit, func() {
+		if _, err := sessionModel.RevokeAll(id); err != nil {
+			log.Printf("Failed to revoke the sessions of user %s: %v", id, err)
+		}
+	})
+}
+
+// sessionClient describes the client of the request for its session. The
+// device defaults to one guessed from the User-Agent.
+func sessionClient(c *gin.Context, device string) models.SessionClient {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	device = strings.TrimSpace(device)
+	if device == "" {
+		device = deviceName(userAgent)
+	}
+	return models.SessionClient{Device: device, IP: c.ClientIP(), UserAgent: userAgent}
+}
+
+// userAgentBrowsers and userAgentSystems map User-Agent tokens to names, in
+// the order they are looked for
+var (
+	userAgentBrowsers = [][2]string{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"},
+		{"Safari/", "Safari"}, {"curl/", "curl"}, {"okhttp/", "OkHttp"}, {"Go-http-client/", "Go"},
+	}
+	userAgentSystems = [][2]string{
+		{"Android", "Android"}, {"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	}
+)
+
+// deviceName names the browser and system of a User-Agent, e.g. "Chrome on
+// Windows"
+func deviceName(userAgent string) string {
+	find := func(names [][2]string) string {
+		for _, name := range names {
+			if strings.Contains(userAgent, name[0]) {
+				return name[1]
+			}
+		}
+		return ""
+	}
+
+	browser, system := find(userAgentBrowsers), find(userAgentSystems)
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown device"
+}
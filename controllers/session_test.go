@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashedUserSessionsAreRevoked(t *testing.T) {
+	conn := openTestDB(t)
+	t.Setenv("ACCESS_SECRET", "test-access-secret")
+	t.Setenv("REFRESH_SECRET", "test-refresh-secret")
+	t.Setenv("TOKEN_STORE", db.TokenStoreSQLite)
+	require.NoError(t, db.InitTokenStore())
+
+	r := newTestRouter()
+	pb := new(PocketBaseController)
+	r.POST("/api/collections/:collection/records", pb.CreateRecord)
+	r.DELETE("/api/collections/:collection/records/:id", pb.DeleteRecord)
+	r.POST("/v1/user/login", UserController{}.Login)
+	r.POST("/v1/token/refresh", AuthController{}.Refresh)
+	r.GET("/v1/user/sessions", AuthController{}.TokenValid, SessionController{}.List)
+
+	var user map[string]interface{}
+	status := serveJSON(t, r, http.MethodPost, "/api/collections/users/records", "", map[string]interface{}{
+		"email": "jane@example.com", "username": "jane", "password": "password123", "passwordConfirm": "password123",
+	}, &user)
+	require.Equal(t, http.StatusOK, status, user)
+
+	login := func() models.Token {
+		var response struct {
+			Token models.Token `json:"token"`
+		}
+		status := serveJSON(t, r, http.MethodPost, "/v1/user/login", "",
+			map[string]interface{}{"email": "jane@example.com", "password": "password123"}, &response)
+		require.Equal(t, http.StatusOK, status)
+		return response.Token
+	}
+	sessions := func(token models.Token) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/user/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		return serve(t, r, req, nil)
+	}
+	refresh := func(token models.Token) int {
+		return serveJSON(t, r, http.MethodPost, "/v1/token/refresh", "", gin.H{"refresh_token": token.RefreshToken}, nil)
+	}
+
+	phone, laptop := login(), login()
+	require.Equal(t, http.StatusOK, sessions(phone))
+
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/users/records/"+user["id"].(string), testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	assert.Equal(t, http.StatusUnauthorized, sessions(phone))
+	assert.Equal(t, http.StatusUnauthorized, refresh(phone))
+	assert.Equal(t, http.StatusUnauthorized, refresh(laptop))
+	var active int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM _sessions WHERE revoked IS NULL").Scan(&active))
+	assert.Zero(t, active)
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxLogsDays          = 365
+	maxBatchRequestLimit = 1000
+)
+
+// SettingsController serves the application settings (admin only)
+type SettingsController struct{}
+
+// View godoc
+// @Summary View settings
+// @Description Return the application settings
+// @Tags settings
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Settings
+// @Failure 403 {object} models.PBErrorResponse
+// @Router /api/settings [get]
+func (ctl SettingsController) View(c *gin.Context) {
+	c.JSON(http.StatusOK, models.GetSettings())
+}
+
+// Update godoc
+// @Summary Update settings
+// @Description Change the application settings. Properties left out keep their current value.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.Settings true "Settings to change"
+// @Success 200 {object} models.Settings
+// @Failure 400 {object} models.PBErrorResponse
+// @Failure 403 {object} models.PBErrorResponse
+// @Router /api/settings [patch]
+func (ctl SettingsController) Update(c *gin.Context) {
+	settings := models.GetSettings()
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		respondError(c, errInvalidBody, "")
+		return
+	}
+
+	settings.Meta.AppName = strings.TrimSpace(settings.Meta.AppName)
+	settings.Meta.AppURL = strings.TrimSpace(settings.Meta.AppURL)
+	if errs := validateSettings(settings); len(errs) > 0 {
+		respondError(c, errs, "Failed to update settings.")
+		return
+	}
+
+	if err := models.SaveSettings(db.GetDB().Db, settings); err != nil {
+		respondError(c, err, "Failed to update settings.")
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// validateSettings returns the errors of the settings, keyed by their path
+func validateSettings(settings models.Settings) fieldErrors {
+	errs := fieldErrors{}
+	if settings.Meta.AppName == "" {
+		errs.Add("meta.appName", codeRequired, "Cannot be blank.")
+	}
+	if appURL, err := url.Parse(settings.Meta.AppURL); settings.Meta.AppURL == "" {
+		errs.Add("meta.appURL", codeRequired, "Cannot be blank.")
+	} else if err != nil || (appURL.Scheme != "http" && appURL.Scheme != "https") || appURL.Host == "" {
+		errs.Add("meta.appURL", codeInvalidValue, "Must be a valid url.")
+	}
+	if settings.Logs.MaxDays < 0 || settings.Logs.MaxDays > maxLogsDays {
+		errs.Add("logs.maxDays", codeInvalidValue, fmt.Sprintf("Must be between 0 and %d.", maxLogsDays))
+	}
+	if settings.Trash.RetentionDays < 0 {
+		errs.Add("trash.retentionDays", codeInvalidValue, "Must be 0 or more.")
+	}
+	if settings.Batch.MaxRequests < 1 || settings.Batch.MaxRequests > maxBatchRequestLimit {
+		errs.Add("batch.maxRequests", codeInvalidValue, fmt.Sprintf("Must be between 1 and %d.", maxBatchRequestLimit))
+	}
+	return errs
+}
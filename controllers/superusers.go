@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+)
+
+// Superusers.
+//
+// Records of the _superusers auth collection are admins: they sign in with
+// /api/collections/_superusers/auth-with-password, bypass the API rules and
+// reach the admin-only routes. The ADMIN_TOKEN remains an admin credential
+// so that the first superuser can be created through the records API;
+// SUPERUSER_EMAIL and SUPERUSER_PASSWORD create it at startup instead.
+
+var errLastSuperuser = newAPIError(http.StatusBadRequest, "The last superuser cannot be deleted.")
+
+// EnsureSuperuser creates the superuser configured by SUPERUSER_EMAIL and
+// SUPERUSER_PASSWORD unless a superuser with that email exists, including in
+// the trash. Its password is never changed afterwards.
+func EnsureSuperuser() error {
+	email := strings.TrimSpace(os.Getenv("SUPERUSER_EMAIL"))
+	password := os.Getenv("SUPERUSER_PASSWORD")
+	if email == "" || password == "" {
+		return nil
+	}
+
+	collection, ok := models.FindCollection(models.SuperusersCollectionName)
+	if !ok {
+		return fmt.Errorf("collection %s not found", models.SuperusersCollectionName)
+	}
+
+	var exists bool
+	err := db.GetDB().Db.QueryRow("SELECT EXISTS(SELECT 1 FROM [_superusers] WHERE [email] = ? COLLATE NOCASE)", email).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	request := &recordRequest{admin: true, system: true}
+	err = inTransaction(func(tx *sql.Tx) error {
+		_, err := request.create(tx, collection, map[string]interface{}{
+			"email":           email,
+			"password":        password,
+			"passwordConfirm": password,
+		})
+		return err
+	})
+	if err != nil {
+		request.rolledBack()
+		return err
+	}
+	request.committed()
+
+	log.Printf("Created superuser %s", email)
+	return nil
+}
+
+// checkSuperusersLeft fails the delete of a superuser that leaves none
+func checkSuperusersLeft(tx *sql.Tx, collection *models.Collection) error {
+	if collection.Name != models.SuperusersCollectionName {
+		return nil
+	}
+	var left bool
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%s] WHERE %s)", collection.Table, liveCondition(collection))
+	if err := tx.QueryRow(query).Scan(&left); err != nil {
+		return err
+	}
+	if !left {
+		return errLastSuperuser
+	}
+	return nil
+}
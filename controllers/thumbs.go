@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// Thumbnails of image files are generated on the first request and cached
+// next to the original, as <record dir>/thumbs_<file name>/<size>_<file name>.
+// Sizes follow PocketBase:
+//
+//	WxH  crop to WxH from the center
+//	WxHt crop to WxH from the top
+//	WxHb crop to WxH from the bottom
+//	WxHf fit inside WxH without cropping
+//	0xH  resize to H height, keeping the aspect ratio
+//	Wx0  resize to W width, keeping the aspect ratio
+
+// thumbSizeRegex matches the thumbnail sizes
+var thumbSizeRegex = regexp.MustCompile(`^(\d{1,4})x(\d{1,4})([tbf]?)$`)
+
+// maxThumbSourcePixels bounds the images thumbnails are generated for, as
+// decoding allocates memory for every pixel
+const maxThumbSourcePixels = 50_000_000
+
+// thumbSize is a parsed thumbnail size
+type thumbSize struct {
+	width, height int
+	mode          string // "", "t", "b" or "f"
+}
+
+func parseThumbSize(size string) (thumbSize, bool) {
+	m := thumbSizeRegex.FindStringSubmatch(size)
+	if m == nil {
+		return thumbSize{}, false
+	}
+	width, _ := strconv.Atoi(m[1])
+	height, _ := strconv.Atoi(m[2])
+	if width == 0 && height == 0 || (width == 0 || height == 0) && m[3] != "" {
+		return thumbSize{}, false
+	}
+	return thumbSize{width: width, height: height, mode: m[3]}, true
+}
+
+// thumbsDir returns the directory caching the thumbnails of the file path
+func thumbsDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "thumbs_"+filepath.Base(path))
+}
+
+// thumbnail returns the path and content type of the thumbnail of the image
+// at path, generating it when it is not cached
+func thumbnail(path, size string) (string, string, error) {
+	thumbSize, ok := parseThumbSize(size)
+	if !ok {
+		return "", "", fmt.Errorf("invalid thumb size %q", size)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	config, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return "", "", err
+	}
+	if config.Width*config.Height > maxThumbSourcePixels {
+		return "", "", fmt.Errorf("image too large for a thumbnail: %dx%d", config.Width, config.Height)
+	}
+
+	// WebP images cannot be encoded with the standard library and are
+	// converted to PNG
+	contentType := "image/" + format
+	if format == "webp" {
+		contentType = "image/png"
+	}
+
+	thumbPath := filepath.Join(thumbsDir(path), size+"_"+filepath.Base(path))
+	if _, err := os.Stat(thumbPath); err == nil {
+		return thumbPath, contentType, nil
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return "", "", err
+	}
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return "", "", err
+	}
+	thumb := resizeImage(src, thumbSize)
+
+	if err := os.MkdirAll(thumbsDir(path), 0755); err != nil {
+		return "", "", err
+	}
+	// Concurrent requests may generate the same thumbnail; each writes its
+	// own file and the rename keeps one of them
+	tmp, err := os.CreateTemp(thumbsDir(path), ".tmp_*")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(tmp, thumb, &jpeg.Options{Quality: 85})
+	case "gif":
+		err = gif.Encode(tmp, thumb, nil)
+	default:
+		err = png.Encode(tmp, thumb)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.Rename(tmp.Name(), thumbPath); err != nil {
+		return "", "", err
+	}
+	return thumbPath, contentType, nil
+}
+
+// resizeImage scales src to size, cropping the part of src that does not
+// fit the aspect ratio of size unless it fits the image inside it
+func resizeImage(src image.Image, size thumbSize) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	width, height := size.width, size.height
+
+	switch {
+	case width == 0:
+		width = max(1, srcW*height/srcH)
+	case height == 0:
+		height = max(1, srcH*width/srcW)
+	case size.mode == "f":
+		if srcW*height > srcH*width {
+			height = max(1, srcH*width/srcW)
+		} else {
+			width = max(1, srcW*height/srcH)
+		}
+	default:
+		// Crop the source to the aspect ratio of the thumbnail
+		cropW, cropH := srcW, srcH
+		if srcW*height > srcH*width {
+			cropW = srcH * width / height
+		} else {
+			cropH = srcW * height / width
+		}
+		x := bounds.Min.X + (srcW-cropW)/2
+		y := bounds.Min.Y + (srcH-cropH)/2
+		switch size.mode {
+		case "t":
+			y = bounds.Min.Y
+		case "b":
+			y = bounds.Max.Y - cropH
+		}
+		bounds = image.Rect(x, y, x+cropW, y+cropH)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	return dst
+}
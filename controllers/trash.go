@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Deleting a record moves it to the trash by setting its deleted timestamp.
+// Trashed records are hidden from every record API, including relations,
+// expands and filters, but keep their files. Admins can list and restore
+// them until they are purged, after the retention days of the trash settings
+// or on request. See relations.go for the records referencing them.
+
+const trashPurgeInterval = time.Hour
+
+// TrashController handles the trashed records of a collection (admin only)
+type TrashController struct{}
+
+// List godoc
+// @Summary List trashed records
+// @Description List the deleted records of a collection, most recently deleted first. Items carry their deleted timestamp.
+// @Tags trash
+// @Produce json
+// @Security BearerAuth
+// @Param collection path string true "Collection name"
+// @Param page query int false "Page number" default(1)
+// @Param perPage query int false "Records per page" default(30)
+// @Param skipTotal query bool false "Skip counting, totalItems and totalPages are -1"
+// @Param filter query string false "Filter query"
+// @Param expand query string false "Expand relations"
+// @Param fields query string false "Fields to return"
+// @Success 200 {object} models.PBListResponse
+// @Router /api/collections/{collection}/trash [get]
+func (ctl TrashController) List(c *gin.Context) {
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	paging, param, err := parsePaging(c)
+	if err != nil {
+		respondInvalidQuery(c, param, err)
+		return
+	}
+	if paging.cursor != nil {
+		respondInvalidQuery(c, "cursor", errors.New("is not supported by the trash"))
+		return
+	}
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
+	}
+
+	conn := db.GetDB().Db
+	request := newRecordRequest(c, nil)
+
+	whereClause, args, err := buildFilterClause(c.Query("filter"), request.resolver(collection))
+	if err != nil {
+		respondInvalidQuery(c, "filter", err)
+		return
+	}
+	whereClause = andWhere(whereClause, fmt.Sprintf("[%s].[deleted] IS NOT NULL", collection.Table))
+
+	totalItems, totalPages := -1, -1
+	if !paging.skipTotal {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM [%s] %s", collection.Table, whereClause)
+		if err := conn.QueryRow(countQuery, args...).Scan(&totalItems); err != nil {
+			respondError(c, err, "Failed to count records.")
+			return
+		}
+		totalPages = (totalItems + paging.perPage - 1) / paging.perPage
+	}
+
+	clauses := fmt.Sprintf("%s ORDER BY [%[2]s].[deleted] DESC, [%[2]s].[id] LIMIT ? OFFSET ?", whereClause, collection.Table)
+	records, err := queryTrashedRecords(conn, collection, clauses, append(args, paging.perPage, (paging.page-1)*paging.perPage)...)
+	if err != nil {
+		respondError(c, err, "Failed to fetch records.")
+		return
+	}
+	records, err = opts.prepare(conn, request, collection, records)
+	if err != nil {
+		respondError(c, err, "Failed to expand records.")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PBListResponse{
+		Page:       paging.page,
+		PerPage:    paging.perPage,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Items:      records,
+	})
+}
+
+// Restore godoc
+// @Summary Restore trashed record
+// @Description Move a deleted record out of the trash. Subscribers are notified as if it was created. Fails with a field error when a live record holds one of its unique values or when a record it relates to is in the trash. The records trashed along with it are not restored.
+// @Tags trash
+// @Produce json
+// @Security BearerAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Record ID"
+// @Param expand query string false "Expand relations"
+// @Param fields query string false "Fields to return"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/collections/{collection}/trash/{id}/restore [post]
+func (ctl TrashController) Restore(c *gin.Context) {
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := parseResponseOptions(c)
+	if !ok {
+		return
+	}
+
+	request := newRecordRequest(c, nil)
+
+	var record models.Record
+	err := inTransaction(func(tx *sql.Tx) error {
+		query := fmt.Sprintf("UPDATE [%s] SET [deleted] = NULL, [updated] = ? WHERE [id] = ? AND [deleted] IS NOT NULL", collection.Table)
+		result, err := tx.Exec(query, time.Now().UTC(), c.Param("id"))
+		if err != nil {
+			// e.g. a live record took the unique values of the trashed one
+			return constraintError(tx, collection, nil, err)
+		}
+		if restored, _ := result.RowsAffected(); restored == 0 {
+			return sql.ErrNoRows
+		}
+
+		record, err = findRecord(tx, collection, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		errs := fieldErrors{}
+		for _, field := range collection.Fields {
+			if _, err := validateRelation(tx, errs, field, record[field.Name]); err != nil {
+				return err
+			}
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+		if err := request.audit(tx, models.AuditOperationRestore, collection, record.ID(), nil, record, nil); err != nil {
+			return err
+		}
+		request.afterCommit = append(request.afterCommit,
+			realtimeClients.recordEvent(realtimeActionCreate, collection, record))
+		return nil
+	})
+	if err != nil {
+		request.rolledBack()
+		respondError(c, err, "Failed to restore record.")
+		return
+	}
+	request.committed()
+
+	respondRecord(c, opts, db.GetDB().Db, request, collection, record)
+}
+
+// Purge godoc
+// @Summary Purge trashed record
+// @Description Permanently delete a record in the trash and its files, along with the trashed records referencing it through CASCADE relations. Fails with 400 while live records reference it through such relations.
+// @Tags trash
+// @Security BearerAuth
+// @Param collection path string true "Collection name"
+// @Param id path string true "Record ID"
+// @Success 204
+// @Router /api/collections/{collection}/trash/{id} [delete]
+func (ctl TrashController) Purge(c *gin.Context) {
+	collection, ok := findCollection(c)
+	if !ok {
+		return
+	}
+
+	request := newRecordRequest(c, nil)
+
+	err := inTransaction(func(tx *sql.Tx) error {
+		return request.purge(tx, collection, c.Param("id"))
+	})
+	if err != nil {
+		request.rolledBack()
+		respondError(c, err, "Failed to purge record.")
+		return
+	}
+	request.committed()
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// queryTrashedRecords runs a SELECT over the collection table like
+// queryRecords, adding the deleted timestamp to the records
+func queryTrashedRecords(q querier, collection *models.Collection, clauses string, args ...interface{}) ([]models.Record, error) {
+	query := fmt.Sprintf("SELECT %s, [%s].[deleted] FROM [%s] %s", recordColumns(collection), collection.Table, collection.Table, clauses)
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.Record{}
+	for rows.Next() {
+		var deleted nullDate
+		record, err := scanRecord(collection, func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &deleted)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		record["deleted"] = deleted.Time
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// purge permanently deletes a trashed record, removing its files once the
+// transaction commits. The records referencing it through CASCADE foreign
+// keys are purged first, see purgeReferences.
+func (r *recordRequest) purge(tx *sql.Tx, collection *models.Collection, id string) error {
+	var trashed bool
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%s] WHERE [id] = ? AND [deleted] IS NOT NULL)", collection.Table)
+	if err := tx.QueryRow(query, id).Scan(&trashed); err != nil {
+		return err
+	}
+	if !trashed {
+		return sql.ErrNoRows
+	}
+	if err := r.purgeReferences(tx, collection, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(fmt.Sprintf("DELETE FROM [%s] WHERE [id] = ? AND [deleted] IS NOT NULL", collection.Table), id)
+	if err != nil {
+		return constraintError(tx, collection, nil, err)
+	}
+	if purged, _ := result.RowsAffected(); purged == 0 {
+		return sql.ErrNoRows
+	}
+	r.removeRecordFiles(collection, id)
+	return r.audit(tx, models.AuditOperationPurge, collection, id, nil, nil, nil)
+}
+
+// trashRetention returns how long records stay in the trash, from the trash
+// settings. Zero keeps them until they are purged on request.
+func trashRetention() time.Duration {
+	return time.Duration(models.GetSettings().Trash.RetentionDays) * 24 * time.Hour
+}
+
+// StartTrashPurge purges the records that stayed in the trash longer than
+// the retention window, now and then every hour
+func StartTrashPurge() {
+	go func() {
+		for {
+			if retention := trashRetention(); retention > 0 {
+				if purged := PurgeTrash(time.Now().Add(-retention)); purged > 0 {
+					log.Printf("Purged %d records from the trash", purged)
+				}
+			}
+			time.Sleep(trashPurgeInterval)
+		}
+	}()
+}
+
+// PurgeTrash permanently deletes the records of every collection that were
+// moved to the trash before cutoff, returning how many were purged. Records
+// that cannot be purged, e.g. because other records still reference them,
+// are logged and left in the trash.
+func PurgeTrash(cutoff time.Time) int {
+	conn := db.GetDB().Db
+	purged := 0
+	for _, collection := range models.Collections() {
+		rows, err := conn.Query(fmt.Sprintf("SELECT [id] FROM [%s] WHERE [deleted] < ?", collection.Table), cutoff.UTC())
+		if err != nil {
+			log.Printf("Failed to list the trash of %s: %v", collection.Name, err)
+			continue
+		}
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		rows.Close()
+
+		for _, id := range ids {
+			request := &recordRequest{admin: true, system: true}
+			err := inTransaction(func(tx *sql.Tx) error {
+				return request.purge(tx, collection, id)
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				// purged along with a record it referenced
+				request.rolledBack()
+				continue
+			}
+			if err != nil {
+				request.rolledBack()
+				log.Printf("Failed to purge %s/%s: %v", collection.Name, id, err)
+				continue
+			}
+			request.committed()
+			purged++
+		}
+	}
+	return purged
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashedRecordsReleaseUniqueValues(t *testing.T) {
+	conn := openTestDB(t)
+	r := newTestRouter()
+	r.DELETE("/api/collections/:collection/records/:id", new(PocketBaseController).DeleteRecord)
+	r.POST("/api/collections/:collection/records", new(PocketBaseController).CreateRecord)
+	r.POST("/api/collections/:collection/trash/:id/restore", TrashController{}.Restore)
+
+	status := serveJSON(t, r, http.MethodDelete, "/api/collections/categories/records/cat_shoes", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	var created map[string]interface{}
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/categories/records", testAdminToken,
+		map[string]interface{}{"name": "Sneakers", "slug": "shoes"}, &created)
+	require.Equal(t, http.StatusOK, status, created)
+
+	var failed struct {
+		Data map[string]struct {
+			Code string `json:"code"`
+		} `json:"data"`
+	}
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/categories/trash/cat_shoes/restore", testAdminToken, nil, &failed)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, codeNotUnique, failed.Data["slug"].Code)
+
+	var deleted bool
+	require.NoError(t, conn.QueryRow("SELECT deleted IS NOT NULL FROM categories WHERE id = 'cat_shoes'").Scan(&deleted))
+	assert.True(t, deleted)
+}
+
+func TestRestoreRequiresLiveRelations(t *testing.T) {
+	conn := openTestDB(t)
+	r := newTestRouter()
+	r.DELETE("/api/collections/:collection/records/:id", new(PocketBaseController).DeleteRecord)
+	r.POST("/api/collections/:collection/trash/:id/restore", TrashController{}.Restore)
+
+	status := serveJSON(t, r, http.MethodDelete, "/api/collections/stores/records/store_sample_123", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	var failed struct {
+		Data map[string]struct {
+			Code string `json:"code"`
+		} `json:"data"`
+	}
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/products/trash/prod_deck_001/restore", testAdminToken, nil, &failed)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, codeMissingRelation, failed.Data["store"].Code)
+
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/stores/trash/store_sample_123/restore", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusOK, status)
+	var deleted bool
+	require.NoError(t, conn.QueryRow("SELECT deleted IS NOT NULL FROM products WHERE id = 'prod_deck_001'").Scan(&deleted))
+	assert.True(t, deleted, "records trashed along with the store stay in the trash")
+
+	status = serveJSON(t, r, http.MethodPost, "/api/collections/products/trash/prod_deck_001/restore", testAdminToken, nil, nil)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestPurgeCascadesThroughTheTrash(t *testing.T) {
+	conn := openTestDB(t)
+	r := newTestRouter()
+	r.DELETE("/api/collections/:collection/records/:id", new(PocketBaseController).DeleteRecord)
+	r.DELETE("/api/collections/:collection/trash/:id", TrashController{}.Purge)
+
+	status := serveJSON(t, r, http.MethodDelete, "/api/collections/stores/records/store_sample_123", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	// A live product of the trashed store blocks the purge
+	_, err := conn.Exec("UPDATE products SET deleted = NULL WHERE id = 'prod_deck_001'")
+	require.NoError(t, err)
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/stores/trash/store_sample_123", testAdminToken, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	var count int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM products WHERE store = 'store_sample_123'").Scan(&count))
+	assert.Equal(t, 3, count)
+
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/products/records/prod_deck_001", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+	status = serveJSON(t, r, http.MethodDelete, "/api/collections/stores/trash/store_sample_123", testAdminToken, nil, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM products WHERE store = 'store_sample_123'").Scan(&count))
+	assert.Zero(t, count)
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM _audit_log WHERE operation = 'purge'").Scan(&count))
+	assert.Equal(t, 4, count, "the store and its products are logged as purged")
+}
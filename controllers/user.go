@@ -1,6 +1,15 @@
 package controllers
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"regexp"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/db"
 	"github.com/VieShare/vieshare-gin/forms"
 	"github.com/VieShare/vieshare-gin/models"
 
@@ -16,9 +25,9 @@ var userModel = new(models.UserModel)
 var userForm = new(forms.UserForm)
 
 // getUserID ...
-func getUserID(c *gin.Context) (userID int64) {
+func getUserID(c *gin.Context) (userID string) {
 	//MustGet returns the value for the given key if it exists, otherwise it panics.
-	return c.MustGet("userID").(int64)
+	return c.MustGet("userID").(string)
 }
 
 // Login User godoc
@@ -31,6 +40,7 @@ func getUserID(c *gin.Context) (userID int64) {
 // @Param article body forms.LoginForm true "User"
 // @Success 	 200  {object}  models.UserLoginResponse
 // @Failure      406  {object}  models.MessageResponse
+// @Failure      503  {object}  models.MessageResponse
 // @Router /user/login [post]
 func (ctrl UserController) Login(c *gin.Context) {
 	var loginForm forms.LoginForm
@@ -41,7 +51,12 @@ func (ctrl UserController) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := userModel.Login(loginForm)
+	user, token, err := userModel.Login(loginForm, sessionClient(c, loginForm.Device))
+	if errors.Is(err, models.ErrTokenStore) {
+		log.Printf("Failed to store login tokens: %v", err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"message": "Login is temporarily unavailable, please try again later"})
+		return
+	}
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusNotAcceptable, gin.H{"message": "Invalid login details"})
 		return
@@ -70,7 +85,7 @@ func (ctrl UserController) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := userModel.Register(registerForm)
+	user, err := registerUser(c, registerForm)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusNotAcceptable, gin.H{"message": err.Error()})
 		return
@@ -97,11 +112,108 @@ func (ctrl UserController) Logout(c *gin.Context) {
 		return
 	}
 
-	deleted, delErr := authModel.DeleteAuth(au.AccessUUID)
-	if delErr != nil || deleted == 0 { //if any goes wrong
+	//Tokens issued before sessions existed only have their own entry to delete
+	if au.SessionID == "" {
+		deleted, delErr := authModel.DeleteAuth(au.AccessUUID)
+		if delErr != nil || deleted == 0 { //if any goes wrong
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid request"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Successfully logged out"})
+		return
+	}
+
+	//The access token must still be live, then its whole session ends
+	if _, err := authModel.FetchAuth(au); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid request"})
+		return
+	}
+	if err := sessionModel.Revoke(au.UserID, au.SessionID); err != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid request"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully logged out"})
 }
+
+// errRegistrationFailed is the v1 message of unexpected registration errors
+var errRegistrationFailed = errors.New("something went wrong, please try again later")
+
+// maxUsernameAttempts bounds the search for a free username
+const maxUsernameAttempts = 10
+
+// usernameRegex matches the characters kept from an email to build a username
+var usernameRegex = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// registerUser creates the users record of a v1 registration through the
+// record API, so that its validation, hooks and audit log apply, and reports
+// failures with the v1 messages
+func registerUser(c *gin.Context, form forms.RegisterForm) (user models.LegacyUser, err error) {
+	users, ok := models.FindCollection("users")
+	if !ok {
+		return user, errRegistrationFailed
+	}
+
+	email := strings.ToLower(form.Email)
+	username, err := availableUsername(db.GetDB().Db, users, email)
+	if err != nil {
+		return user, errRegistrationFailed
+	}
+
+	data := map[string]interface{}{
+		"email":           email,
+		"username":        username,
+		"name":            form.Name,
+		"password":        form.Password,
+		"passwordConfirm": form.Password,
+	}
+	request := newRecordRequest(c, data)
+
+	var record models.Record
+	err = inTransaction(func(tx *sql.Tx) (err error) {
+		record, err = request.create(tx, users, data)
+		return err
+	})
+	if err != nil {
+		request.rolledBack()
+		var fieldErrs fieldErrors
+		if errors.As(err, &fieldErrs) {
+			if fe, ok := fieldErrs["email"]; ok && fe.Code == codeNotUnique {
+				return user, errors.New("email already exists")
+			}
+			if _, ok := fieldErrs["email"]; ok {
+				return user, errors.New(userForm.Email("email"))
+			}
+			if _, ok := fieldErrs["password"]; ok {
+				return user, errors.New(userForm.Password("min"))
+			}
+		}
+		return user, errRegistrationFailed
+	}
+	request.committed()
+
+	return models.LegacyUser{ID: record.ID(), Email: email, Name: form.Name}, nil
+}
+
+// availableUsername derives an unused username from the local part of an
+// email, adding random digits when it is taken
+func availableUsername(q querier, users *models.Collection, email string) (string, error) {
+	base := usernameRegex.ReplaceAllString(strings.ToLower(strings.Split(email, "@")[0]), "")
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for i := 0; i < maxUsernameAttempts; i++ {
+		var taken bool
+		query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM [%s] WHERE [username] = ? COLLATE NOCASE)", users.Table)
+		if err := q.QueryRow(query, username).Scan(&taken); err != nil {
+			return "", err
+		}
+		if !taken {
+			return username, nil
+		}
+		username = fmt.Sprintf("%s%d", base, 1000+rand.Intn(9000))
+	}
+	return "", errors.New("no username available")
+}
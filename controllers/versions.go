@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/models"
+)
+
+// Optimistic concurrency control.
+//
+// The version of a record is its updated timestamp, which every write
+// changes. Single record responses carry it as a strong ETag, the quoted
+// RFC 3339 value of updated, e.g. "2025-01-01T10:00:00.123456789Z", so that
+// clients can also build it from the updated field of listed records.
+// Updates and deletes sending If-Match only apply to the matching version,
+// and collections with requireIfMatch reject writes without it.
+
+var (
+	errVersionMismatch = newAPIError(http.StatusPreconditionFailed, "The record was modified since it was fetched, reload it and try again.")
+	errVersionRequired = newAPIError(http.StatusPreconditionRequired, "The If-Match header with the record ETag is required to modify records of this collection.")
+)
+
+// recordETag returns the ETag of a record, or "" when it has no version
+func recordETag(record models.Record) string {
+	updated, ok := record["updated"].(time.Time)
+	if !ok {
+		return ""
+	}
+	return `"` + updated.UTC().Format(time.RFC3339Nano) + `"`
+}
+
+// checkVersion checks the If-Match header of r against the current version
+// of a record about to be modified
+func (r *recordRequest) checkVersion(collection *models.Collection, current models.Record) error {
+	header := strings.TrimSpace(r.headers.Get("If-Match"))
+	if header == "" {
+		if collection.RequireIfMatch {
+			return errVersionRequired
+		}
+		return nil
+	}
+	if !etagMatches(header, recordETag(current)) {
+		return errVersionMismatch
+	}
+	return nil
+}
+
+// etagMatches reports whether an If-Match header lists etag, using the strong
+// comparison of RFC 9110: weak tags never match. Unquoted tags are accepted
+// for clients sending the updated value as is.
+func etagMatches(header, etag string) bool {
+	if header == "*" {
+		return etag != ""
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if !strings.HasPrefix(tag, `"`) {
+			tag = `"` + tag + `"`
+		}
+		if etag != "" && tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveIfMatch sends an admin request with a JSON body and an If-Match
+// header, when not empty, returning the response
+func serveIfMatch(t *testing.T, r http.Handler, method, path, ifMatch string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	encoded, err := json.Marshal(body)
+	require.NoError(t, err)
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testAdminToken)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIfMatchRejectsStaleVersions(t *testing.T) {
+	conn := openTestDB(t)
+	r := newRecordsTestRouter()
+	path := "/api/collections/products/records/prod_deck_001"
+
+	w := serveIfMatch(t, r, http.MethodGet, path, "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = serveIfMatch(t, r, http.MethodPatch, path, etag, map[string]interface{}{"inventory": 23})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	current := w.Header().Get("ETag")
+	assert.NotEqual(t, etag, current)
+
+	w = serveIfMatch(t, r, http.MethodPatch, path, etag, map[string]interface{}{"inventory": 5})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = serveIfMatch(t, r, http.MethodPatch, path, "W/"+current, map[string]interface{}{"inventory": 5})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code, "weak tags never match")
+	w = serveIfMatch(t, r, http.MethodDelete, path, etag, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	var inventory int
+	var deleted bool
+	require.NoError(t, conn.QueryRow("SELECT inventory, deleted IS NOT NULL FROM products WHERE id = 'prod_deck_001'").Scan(&inventory, &deleted))
+	assert.Equal(t, 23, inventory)
+	assert.False(t, deleted)
+
+	w = serveIfMatch(t, r, http.MethodPatch, path, "*", map[string]interface{}{"inventory": 5})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serveIfMatch(t, r, http.MethodDelete, path, w.Header().Get("ETag"), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	openTestDB(t)
+	r := newRecordsTestRouter()
+	path := "/api/collections/products/records/prod_deck_001"
+
+	products, ok := models.FindCollection("products")
+	require.True(t, ok)
+	required := products.Clone()
+	required.RequireIfMatch = true
+	models.RegisterCollection(required)
+	t.Cleanup(func() { models.RegisterCollection(products) })
+
+	w := serveIfMatch(t, r, http.MethodPatch, path, "", map[string]interface{}{"inventory": 5})
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	w = serveIfMatch(t, r, http.MethodDelete, path, "", nil)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	w = serveIfMatch(t, r, http.MethodPatch, path, "*", map[string]interface{}{"inventory": 5})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `"2025-01-01T10:00:00.123456789Z"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{etag, true},
+		{"2025-01-01T10:00:00.123456789Z", true},
+		{`"other", ` + etag, true},
+		{"*", true},
+		{`"2025-01-01T10:00:00.123Z"`, false},
+		{"W/" + etag, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, etagMatches(tt.header, etag), tt.header)
+	}
+	assert.False(t, etagMatches("*", ""))
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-gorp/gorp"
 	_redis "github.com/go-redis/redis/v7"
@@ -42,7 +43,12 @@ func Init() {
 
 //ConnectDB ...
 func ConnectDB(dataSourceName string) (*gorp.DbMap, error) {
-	db, err := sql.Open("sqlite3", dataSourceName)
+	// Enforce the foreign keys on every connection of the pool
+	separator := "?"
+	if strings.Contains(dataSourceName, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite3", dataSourceName+separator+"_foreign_keys=1")
 	if err != nil {
 		return nil, err
 	}
@@ -64,8 +70,9 @@ func GetDB() *gorp.DbMap {
 //RedisClient ...
 var RedisClient *_redis.Client
 
-//InitRedis ...
-func InitRedis(selectDB ...int) {
+//InitRedis connects to the Redis server of REDIS_HOST and checks that it
+//answers
+func InitRedis(selectDB ...int) error {
 
 	var redisHost = os.Getenv("REDIS_HOST")
 	var redisPassword = os.Getenv("REDIS_PASSWORD")
@@ -86,6 +93,7 @@ func InitRedis(selectDB ...int) {
 		// },
 	})
 
+	return RedisClient.Ping().Err()
 }
 
 //GetRedis ...
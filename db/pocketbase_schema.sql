@@ -17,7 +17,8 @@ CREATE TABLE users (
     username TEXT NOT NULL UNIQUE,
     name TEXT,
     avatar TEXT,
-    verified BOOLEAN DEFAULT FALSE
+    verified BOOLEAN DEFAULT FALSE,
+    password TEXT
 );
 
 -- Categories table
@@ -212,69 +213,69 @@ CREATE INDEX idx_carts_session_id ON carts(session_id);
 
 -- Create triggers for updating timestamps
 CREATE TRIGGER update_users_updated_at 
-AFTER UPDATE ON users FOR EACH ROW 
+AFTER UPDATE ON users FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE users SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE users SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_categories_updated_at 
-AFTER UPDATE ON categories FOR EACH ROW 
+AFTER UPDATE ON categories FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE categories SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE categories SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_subcategories_updated_at 
-AFTER UPDATE ON subcategories FOR EACH ROW 
+AFTER UPDATE ON subcategories FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE subcategories SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE subcategories SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_stores_updated_at 
-AFTER UPDATE ON stores FOR EACH ROW 
+AFTER UPDATE ON stores FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE stores SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE stores SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_products_updated_at 
-AFTER UPDATE ON products FOR EACH ROW 
+AFTER UPDATE ON products FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE products SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE products SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_carts_updated_at 
-AFTER UPDATE ON carts FOR EACH ROW 
+AFTER UPDATE ON carts FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE carts SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE carts SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_cart_items_updated_at 
-AFTER UPDATE ON cart_items FOR EACH ROW 
+AFTER UPDATE ON cart_items FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE cart_items SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE cart_items SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_addresses_updated_at 
-AFTER UPDATE ON addresses FOR EACH ROW 
+AFTER UPDATE ON addresses FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE addresses SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE addresses SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_orders_updated_at 
-AFTER UPDATE ON orders FOR EACH ROW 
+AFTER UPDATE ON orders FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE orders SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE orders SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_customers_updated_at 
-AFTER UPDATE ON customers FOR EACH ROW 
+AFTER UPDATE ON customers FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE customers SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE customers SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 CREATE TRIGGER update_notifications_updated_at 
-AFTER UPDATE ON notifications FOR EACH ROW 
+AFTER UPDATE ON notifications FOR EACH ROW WHEN NEW.updated IS OLD.updated
 BEGIN 
-    UPDATE notifications SET updated = CURRENT_TIMESTAMP WHERE id = NEW.id; 
+    UPDATE notifications SET updated = strftime('%Y-%m-%d %H:%M:%fZ', 'now') WHERE id = NEW.id; 
 END;
 
 -- Insert sample data
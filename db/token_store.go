@@ -0,0 +1,263 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	_redis "github.com/go-redis/redis/v7"
+)
+
+// Token stores.
+//
+// The v1 auth API keeps the ids of the access and refresh tokens it issues,
+// with the user they belong to, until they expire or the user logs out.
+// TOKEN_STORE selects where: "redis" (REDIS_HOST, database 1), "sqlite" (the
+// _tokens table of the application database) or "memory" (lost on restart,
+// for development and tests). It defaults to redis when REDIS_HOST is set and
+// to sqlite otherwise.
+
+// Token store kinds accepted in TOKEN_STORE
+const (
+	TokenStoreRedis  = "redis"
+	TokenStoreSQLite = "sqlite"
+	TokenStoreMemory = "memory"
+)
+
+// redisTokenDB is the Redis database holding the tokens
+const redisTokenDB = 1
+
+// tokenSweepInterval is how often the sqlite and memory stores delete
+// expired tokens
+const tokenSweepInterval = time.Minute
+
+// ErrTokenNotFound is returned for tokens that were never stored, expired or
+// were deleted
+var ErrTokenNotFound = errors.New("token not found")
+
+// TokenStore keeps values, such as the user id of a token, under a key until
+// their time to live runs out
+type TokenStore interface {
+	Set(key, value string, ttl time.Duration) error
+	Get(key string) (string, error)
+	// Delete removes the keys and returns how many existed
+	Delete(keys ...string) (int64, error)
+	// Ping checks that the store can be reached
+	Ping() error
+}
+
+var tokenStore TokenStore
+
+// InitTokenStore opens the token store selected by TOKEN_STORE and checks
+// that it answers
+func InitTokenStore() error {
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv("TOKEN_STORE")))
+	if kind == "" {
+		kind = TokenStoreSQLite
+		if os.Getenv("REDIS_HOST") != "" {
+			kind = TokenStoreRedis
+		}
+	}
+
+	var store TokenStore
+	switch kind {
+	case TokenStoreRedis:
+		if err := InitRedis(redisTokenDB); err != nil {
+			return fmt.Errorf("redis token store at %q is unreachable: %w", os.Getenv("REDIS_HOST"), err)
+		}
+		store = &redisTokenStore{client: GetRedis()}
+	case TokenStoreSQLite:
+		sqliteStore, err := newSQLiteTokenStore(GetDB().Db)
+		if err != nil {
+			return fmt.Errorf("sqlite token store: %w", err)
+		}
+		store = sqliteStore
+	case TokenStoreMemory:
+		store = newMemoryTokenStore()
+	default:
+		return fmt.Errorf("unknown TOKEN_STORE %q, expected %s, %s or %s", kind, TokenStoreRedis, TokenStoreSQLite, TokenStoreMemory)
+	}
+
+	if err := store.Ping(); err != nil {
+		return fmt.Errorf("%s token store is unreachable: %w", kind, err)
+	}
+	tokenStore = store
+	return nil
+}
+
+// GetTokenStore returns the token store opened by InitTokenStore
+func GetTokenStore() TokenStore {
+	return tokenStore
+}
+
+// redisTokenStore keeps the tokens in Redis, which expires them itself
+type redisTokenStore struct {
+	client *_redis.Client
+}
+
+func (s *redisTokenStore) Set(key, value string, ttl time.Duration) error {
+	return s.client.Set(key, value, ttl).Err()
+}
+
+func (s *redisTokenStore) Get(key string) (string, error) {
+	value, err := s.client.Get(key).Result()
+	if errors.Is(err, _redis.Nil) {
+		return "", ErrTokenNotFound
+	}
+	return value, err
+}
+
+func (s *redisTokenStore) Delete(keys ...string) (int64, error) {
+	return s.client.Del(keys...).Result()
+}
+
+func (s *redisTokenStore) Ping() error {
+	return s.client.Ping().Err()
+}
+
+// TokensTableSQL creates the table of the sqlite store. It is applied by the
+// system migrations of the application database, before the store opens.
+const TokensTableSQL = `
+CREATE TABLE IF NOT EXISTS _tokens (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    expires INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tokens_expires ON _tokens (expires);`
+
+// sqliteTokenStore keeps the tokens in the _tokens table. Expiry times are
+// Unix milliseconds; expired rows are ignored and deleted now and then.
+type sqliteTokenStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func newSQLiteTokenStore(conn *sql.DB) (*sqliteTokenStore, error) {
+	var exists bool
+	if err := conn.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = '_tokens')").Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("the _tokens table is missing, the database migrations must run first")
+	}
+	return &sqliteTokenStore{db: conn}, nil
+}
+
+func (s *sqliteTokenStore) Set(key, value string, ttl time.Duration) error {
+	s.sweep()
+	_, err := s.db.Exec(`INSERT INTO _tokens (key, value, expires) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires = excluded.expires`,
+		key, value, time.Now().Add(ttl).UnixMilli())
+	return err
+}
+
+func (s *sqliteTokenStore) Get(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM _tokens WHERE key = ? AND expires > ?", key, time.Now().UnixMilli()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTokenNotFound
+	}
+	return value, err
+}
+
+func (s *sqliteTokenStore) Delete(keys ...string) (int64, error) {
+	var deleted int64
+	for _, key := range keys {
+		result, err := s.db.Exec("DELETE FROM _tokens WHERE key = ? AND expires > ?", key, time.Now().UnixMilli())
+		if err != nil {
+			return deleted, err
+		}
+		n, _ := result.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
+
+func (s *sqliteTokenStore) Ping() error {
+	return s.db.Ping()
+}
+
+// sweep deletes the expired tokens, at most once per tokenSweepInterval
+func (s *sqliteTokenStore) sweep() {
+	s.mu.Lock()
+	if time.Since(s.lastSweep) < tokenSweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.mu.Unlock()
+
+	s.db.Exec("DELETE FROM _tokens WHERE expires <= ?", time.Now().UnixMilli())
+}
+
+// memoryTokenStore keeps the tokens in a map of the process
+type memoryTokenStore struct {
+	mu        sync.Mutex
+	tokens    map[string]memoryToken
+	lastSweep time.Time
+}
+
+type memoryToken struct {
+	value   string
+	expires time.Time
+}
+
+func newMemoryTokenStore() *memoryTokenStore {
+	return &memoryTokenStore{tokens: map[string]memoryToken{}}
+}
+
+func (s *memoryTokenStore) Set(key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= tokenSweepInterval {
+		for k, token := range s.tokens {
+			if !token.expires.After(now) {
+				delete(s.tokens, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.tokens[key] = memoryToken{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *memoryTokenStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[key]
+	if !ok || !token.expires.After(time.Now()) {
+		return "", ErrTokenNotFound
+	}
+	return token.value, nil
+}
+
+func (s *memoryTokenStore) Delete(keys ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	now := time.Now()
+	for _, key := range keys {
+		if token, ok := s.tokens[key]; ok {
+			if token.expires.After(now) {
+				deleted++
+			}
+			delete(s.tokens, key)
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryTokenStore) Ping() error {
+	return nil
+}
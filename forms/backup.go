@@ -0,0 +1,7 @@
+package forms
+
+// BackupForm creates a backup. Name is the file name of the archive, e.g.
+// "before_migration.zip"; a timestamped name is used when it is empty.
+type BackupForm struct {
+	Name string `json:"name"`
+}
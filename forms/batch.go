@@ -0,0 +1,16 @@
+package forms
+
+// BatchForm is the body of a batch request
+type BatchForm struct {
+	Requests []BatchRequest `json:"requests"`
+}
+
+// BatchRequest is a single record operation inside a batch. URL is the
+// record API path, optionally with expand and fields query parameters.
+// Headers are added to those of the batch request, e.g. If-Match.
+type BatchRequest struct {
+	Method  string                 `json:"method"`
+	URL     string                 `json:"url"`
+	Headers map[string]string      `json:"headers"`
+	Body    map[string]interface{} `json:"body"`
+}
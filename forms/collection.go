@@ -0,0 +1,50 @@
+package forms
+
+import "encoding/json"
+
+// CollectionForm is the body of collection create and update requests.
+// Properties left out of an update keep their current value; when fields is
+// sent it replaces the whole field list.
+type CollectionForm struct {
+	Name           *string            `json:"name"`
+	Fields         *[]CollectionField `json:"fields"`
+	Indexes        *[]string          `json:"indexes"`
+	DefaultSort    *string            `json:"defaultSort"`
+	DefaultFilter  *string            `json:"defaultFilter"`
+	RequireIfMatch *bool              `json:"requireIfMatch"`
+	ListRule       NullableString     `json:"listRule"`
+	ViewRule       NullableString     `json:"viewRule"`
+	CreateRule     NullableString     `json:"createRule"`
+	UpdateRule     NullableString     `json:"updateRule"`
+	DeleteRule     NullableString     `json:"deleteRule"`
+}
+
+// CollectionField describes one field of a CollectionForm. Existing fields
+// are matched by id, or by name when the id is omitted, so renaming a field
+// requires its id.
+type CollectionField struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Required   bool   `json:"required"`
+	Collection string `json:"collection"`
+
+	// Options of file fields
+	MaxSelect int      `json:"maxSelect"`
+	MaxSize   int64    `json:"maxSize"`
+	MimeTypes []string `json:"mimeTypes"`
+	Thumbs    []string `json:"thumbs"`
+}
+
+// NullableString is a string property that distinguishes an explicit null
+// from an omitted property, e.g. to lock an API rule with "listRule": null
+type NullableString struct {
+	Set   bool
+	Value *string
+}
+
+// UnmarshalJSON records that the property was sent
+func (n *NullableString) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	return json.Unmarshal(data, &n.Value)
+}
@@ -0,0 +1,8 @@
+package forms
+
+// RealtimeSubscribeForm replaces the subscriptions of a realtime client. An
+// empty list unsubscribes from every topic.
+type RealtimeSubscribeForm struct {
+	ClientID      string   `json:"clientId"`
+	Subscriptions []string `json:"subscriptions"`
+}
@@ -0,0 +1,8 @@
+package forms
+
+// AuthWithPasswordForm signs in a record of an auth collection. Identity is
+// the email or username of the record.
+type AuthWithPasswordForm struct {
+	Identity string `json:"identity"`
+	Password string `json:"password"`
+}
@@ -12,14 +12,15 @@ type UserForm struct{}
 //LoginForm ...
 type LoginForm struct {
 	Email    string `form:"email" json:"email" binding:"required,email"`
-	Password string `form:"password" json:"password" binding:"required,min=3,max=50"`
+	Password string `form:"password" json:"password" binding:"required"` //Length rules apply on register only, older accounts may have shorter passwords
+	Device   string `form:"device" json:"device" binding:"max=100"` //Shown in the sessions list, guessed from the User-Agent when empty
 }
 
 //RegisterForm ...
 type RegisterForm struct {
 	Name     string `form:"name" json:"name" binding:"required,min=3,max=20,fullName"` //fullName rule is in validator.go
 	Email    string `form:"email" json:"email" binding:"required,email"`
-	Password string `form:"password" json:"password" binding:"required,min=3,max=50"`
+	Password string `form:"password" json:"password" binding:"required,min=8,max=71"`
 }
 
 //Name ...
@@ -60,7 +61,7 @@ func (f UserForm) Password(tag string) (message string) {
 	case "required":
 		return "Please enter your password"
 	case "min", "max":
-		return "Your password should be between 3 and 50 characters"
+		return "Your password should be between 8 and 71 characters"
 	case "eqfield":
 		return "Your passwords does not match"
 	default:
@@ -68,6 +69,16 @@ func (f UserForm) Password(tag string) (message string) {
 	}
 }
 
+//Device ...
+func (f UserForm) Device(tag string) (message string) {
+	switch tag {
+	case "max":
+		return "Your device name should be at most 100 characters"
+	default:
+		return "Something went wrong, please try again later"
+	}
+}
+
 //Signin ...
 func (f UserForm) Login(err error) string {
 	switch err.(type) {
@@ -84,6 +95,9 @@ func (f UserForm) Login(err error) string {
 			if err.Field() == "Password" {
 				return f.Password(err.Tag())
 			}
+			if err.Field() == "Device" {
+				return f.Device(err.Tag())
+			}
 		}
 
 	default:
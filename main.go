@@ -4,9 +4,11 @@ import (
 	"log"
 	"os"
 
+	"github.com/VieShare/vieshare-gin/controllers"
 	"github.com/VieShare/vieshare-gin/db"
 	_ "github.com/VieShare/vieshare-gin/docs"
 	"github.com/VieShare/vieshare-gin/forms"
+	"github.com/VieShare/vieshare-gin/models"
 	"github.com/VieShare/vieshare-gin/routers"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
@@ -53,15 +55,33 @@ func main() {
 	// Setup middlewares
 	r.Use(routers.CORSMiddleware())
 	r.Use(routers.RequestIDMiddleware())
-	r.Use(gzip.Gzip(gzip.DefaultCompression))
+	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/api/realtime"})))
 
 	//Start SQLite3 database
 	//Example: db.GetDB() - More info in the models folder
 	db.Init()
 
-	//Start Redis on database 1 - it's used to store the JWT but you can use it for anythig else
-	//Example: db.GetRedis().Set(KEY, VALUE, at.Sub(now)).Err()
-	db.InitRedis(1)
+	//Load collection schemas from the _collections table
+	if err := models.InitCollections(); err != nil {
+		log.Fatal("Failed to load collections:", err)
+	}
+
+	//Create the superuser of SUPERUSER_EMAIL and SUPERUSER_PASSWORD
+	if err := controllers.EnsureSuperuser(); err != nil {
+		log.Fatal("Failed to create superuser:", err)
+	}
+
+	//Purge the records left in the trash past the trash retention days
+	controllers.StartTrashPurge()
+
+	//Write the request logs and delete those past the logs max days
+	controllers.StartRequestLogs()
+
+	//Open the store of the v1 JWT ids selected by TOKEN_STORE (redis, sqlite or memory)
+	//Example: db.GetTokenStore().Set(KEY, VALUE, at.Sub(now))
+	if err := db.InitTokenStore(); err != nil {
+		log.Fatal("Failed to open the token store: ", err)
+	}
 
 	// Setup V1 API routes
 	v1 := r.Group("/v1")
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Audit log of record changes.
+//
+// Every record create, update and delete made through the API, including
+// batch requests and trash operations, adds an entry to _audit_log in the
+// transaction of the change, so rolled back changes leave no entry. Entries
+// refer to the collection by id, which is kept when it is renamed.
+
+const auditLogTableSQL = `
+CREATE TABLE IF NOT EXISTS _audit_log (
+    id TEXT PRIMARY KEY,
+    created DATETIME NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    actor_type TEXT NOT NULL,
+    actor_collection TEXT NOT NULL DEFAULT '',
+    actor_id TEXT NOT NULL DEFAULT '',
+    collection_id TEXT NOT NULL,
+    record_id TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    changes JSON NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_record ON _audit_log (collection_id, record_id, created);`
+
+// Audit log operations
+const (
+	AuditOperationCreate  = "create"
+	AuditOperationUpdate  = "update"
+	AuditOperationDelete  = "delete"
+	AuditOperationRestore = "restore"
+	AuditOperationPurge   = "purge"
+)
+
+// Audit log actor types
+const (
+	AuditActorAdmin  = "admin"
+	AuditActorRecord = "record"
+	AuditActorGuest  = "guest"
+	AuditActorSystem = "system"
+)
+
+// AuditRedactedValue replaces the values of password fields in the audit log
+const AuditRedactedValue = "******"
+
+// AuditActor identifies who made a change: an admin, an authenticated record,
+// a guest or the system, e.g. the trash purge job. Superusers are admins
+// identified by their collection and id.
+type AuditActor struct {
+	Type       string `json:"type"`
+	Collection string `json:"collection,omitempty"`
+	ID         string `json:"id,omitempty"`
+}
+
+// AuditChange holds the values of a field before and after a change, nil
+// when the record did not exist
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry is a single change of the audit log
+type AuditEntry struct {
+	ID           string                 `json:"id"`
+	Created      time.Time              `json:"created"`
+	RequestID    string                 `json:"requestId"`
+	Actor        AuditActor             `json:"actor"`
+	CollectionID string                 `json:"collectionId"`
+	RecordID     string                 `json:"recordId"`
+	Operation    string                 `json:"operation"`
+	Changes      map[string]AuditChange `json:"changes"`
+}
+
+// createAuditLog creates the audit log table
+func createAuditLog(tx *sql.Tx) error {
+	_, err := tx.Exec(auditLogTableSQL)
+	return err
+}
@@ -1,10 +1,11 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +25,7 @@ type TokenDetails struct {
 	RefreshToken string
 	AccessUUID   string
 	RefreshUUID  string
+	SessionID    string
 	AtExpires    int64
 	RtExpires    int64
 }
@@ -31,7 +33,9 @@ type TokenDetails struct {
 // AccessDetails ...
 type AccessDetails struct {
 	AccessUUID string
-	UserID     int64
+	UserID     string
+	// SessionID is empty for the tokens issued before sessions existed
+	SessionID string
 }
 
 // Token ...
@@ -40,13 +44,26 @@ type Token struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// createTokens creates the table of the sqlite token store
+func createTokens(tx *sql.Tx) error {
+	_, err := tx.Exec(db.TokensTableSQL)
+	return err
+}
+
+// ErrTokenStore is returned when the issued tokens cannot be saved in the
+// token store
+var ErrTokenStore = errors.New("token store unavailable")
+
+// errNotAccessToken is returned for valid tokens that are not v1 access tokens
+var errNotAccessToken = errors.New("not an access token")
+
 // AuthModel ...
 type AuthModel struct{}
 
-// CreateToken ...
-func (m AuthModel) CreateToken(userID int64) (*TokenDetails, error) {
+// CreateToken issues the access and refresh tokens of a session
+func (m AuthModel) CreateToken(userID, sessionID string) (*TokenDetails, error) {
 
-	td := &TokenDetails{}
+	td := &TokenDetails{SessionID: sessionID}
 	td.AtExpires = time.Now().Add(time.Minute * 15).Unix()
 	td.AccessUUID = uuid.New().String()
 
@@ -59,6 +76,7 @@ func (m AuthModel) CreateToken(userID int64) (*TokenDetails, error) {
 	atClaims["authorized"] = true
 	atClaims["access_uuid"] = td.AccessUUID
 	atClaims["user_id"] = userID
+	atClaims["session_id"] = sessionID
 	atClaims["exp"] = td.AtExpires
 
 	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
@@ -70,6 +88,7 @@ func (m AuthModel) CreateToken(userID int64) (*TokenDetails, error) {
 	rtClaims := jwt.MapClaims{}
 	rtClaims["refresh_uuid"] = td.RefreshUUID
 	rtClaims["user_id"] = userID
+	rtClaims["session_id"] = sessionID
 	rtClaims["exp"] = td.RtExpires
 	rt := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
 	td.RefreshToken, err = rt.SignedString([]byte(os.Getenv("REFRESH_SECRET")))
@@ -80,18 +99,18 @@ func (m AuthModel) CreateToken(userID int64) (*TokenDetails, error) {
 }
 
 // CreateAuth ...
-func (m AuthModel) CreateAuth(userid int64, td *TokenDetails) error {
+func (m AuthModel) CreateAuth(userid string, td *TokenDetails) error {
 	at := time.Unix(td.AtExpires, 0) //converting Unix to UTC(to Time object)
 	rt := time.Unix(td.RtExpires, 0)
 	now := time.Now()
 
-	errAccess := db.GetRedis().Set(td.AccessUUID, strconv.Itoa(int(userid)), at.Sub(now)).Err()
+	errAccess := db.GetTokenStore().Set(td.AccessUUID, userid, at.Sub(now))
 	if errAccess != nil {
-		return errAccess
+		return fmt.Errorf("%w: %v", ErrTokenStore, errAccess)
 	}
-	errRefresh := db.GetRedis().Set(td.RefreshUUID, strconv.Itoa(int(userid)), rt.Sub(now)).Err()
+	errRefresh := db.GetTokenStore().Set(td.RefreshUUID, userid, rt.Sub(now))
 	if errRefresh != nil {
-		return errRefresh
+		return fmt.Errorf("%w: %v", ErrTokenStore, errRefresh)
 	}
 	return nil
 }
@@ -143,35 +162,40 @@ func (m AuthModel) ExtractTokenMetadata(r *http.Request) (*AccessDetails, error)
 	}
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if ok && token.Valid {
+		//Record auth tokens are signed with the same secret but are not v1 access tokens
+		if _, isRecordToken := claims["type"]; isRecordToken {
+			return nil, errNotAccessToken
+		}
 		accessUUID, ok := claims["access_uuid"].(string)
-		if !ok {
-			return nil, err
+		if !ok || accessUUID == "" {
+			return nil, errNotAccessToken
 		}
-		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["user_id"]), 10, 64)
-		if err != nil {
-			return nil, err
+		userID, ok := claims["user_id"].(string)
+		if !ok || userID == "" {
+			return nil, errors.New("invalid user id")
 		}
+		sessionID, _ := claims["session_id"].(string)
 		return &AccessDetails{
 			AccessUUID: accessUUID,
 			UserID:     userID,
+			SessionID:  sessionID,
 		}, nil
 	}
-	return nil, err
+	return nil, errNotAccessToken
 }
 
 // FetchAuth ...
-func (m AuthModel) FetchAuth(authD *AccessDetails) (int64, error) {
-	userid, err := db.GetRedis().Get(authD.AccessUUID).Result()
+func (m AuthModel) FetchAuth(authD *AccessDetails) (string, error) {
+	userID, err := db.GetTokenStore().Get(authD.AccessUUID)
 	if err != nil {
-		return 0, err
+		return "", err
 	}
-	userID, _ := strconv.ParseInt(userid, 10, 64)
 	return userID, nil
 }
 
 // DeleteAuth ...
-func (m AuthModel) DeleteAuth(givenUUID string) (int64, error) {
-	deleted, err := db.GetRedis().Del(givenUUID).Result()
+func (m AuthModel) DeleteAuth(givenUUIDs ...string) (int64, error) {
+	deleted, err := db.GetTokenStore().Delete(givenUUIDs...)
 	if err != nil {
 		return 0, err
 	}
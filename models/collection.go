@@ -0,0 +1,231 @@
+package models
+
+import (
+	"sort"
+	"sync"
+)
+
+// FieldType identifies the kind of value stored in a collection field
+type FieldType string
+
+const (
+	FieldTypeText     FieldType = "text"
+	FieldTypeEmail    FieldType = "email"
+	FieldTypeNumber   FieldType = "number"
+	FieldTypeBool     FieldType = "bool"
+	FieldTypeDate     FieldType = "date"
+	FieldTypeJSON     FieldType = "json"
+	FieldTypeRelation FieldType = "relation"
+	FieldTypeFile     FieldType = "file"
+	FieldTypePassword FieldType = "password"
+)
+
+// DefaultFileMaxSize is the size limit of uploads to file fields without a
+// maxSize, in bytes
+const DefaultFileMaxSize int64 = 5 << 20
+
+// Field describes a single column of a collection
+type Field struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Column   string    `json:"-"`
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required"`
+	Relation string    `json:"collection,omitempty"` // target collection for relation fields
+
+	// Options of file fields. MaxSelect is the number of files the field
+	// holds, a single file name when 1 or less and a list otherwise. Empty
+	// MimeTypes accept any file. Thumbs lists the thumbnail sizes that may be
+	// requested for its images, e.g. "100x100" or "600x0".
+	MaxSelect int      `json:"maxSelect,omitempty"`
+	MaxSize   int64    `json:"maxSize,omitempty"`
+	MimeTypes []string `json:"mimeTypes,omitempty"`
+	Thumbs    []string `json:"thumbs,omitempty"`
+}
+
+// ColumnName returns the SQL column backing the field
+func (f Field) ColumnName() string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return f.Name
+}
+
+// IsMultiple reports whether a file field holds a list of files
+func (f Field) IsMultiple() bool {
+	return f.Type == FieldTypeFile && f.MaxSelect > 1
+}
+
+// MaxFileSize returns the size limit of uploads to a file field
+func (f Field) MaxFileSize() int64 {
+	if f.MaxSize > 0 {
+		return f.MaxSize
+	}
+	return DefaultFileMaxSize
+}
+
+// Collection describes a PocketBase-style collection and its schema
+type Collection struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	System bool    `json:"system"`
+	Table  string  `json:"-"`
+	Fields []Field `json:"fields"`
+
+	// Indexes holds the CREATE INDEX statements of the collection table
+	Indexes []string `json:"indexes"`
+
+	// API rules are PocketBase filter expressions checked against the
+	// requested records. A nil rule locks the action to admins and an empty
+	// rule allows everyone.
+	ListRule   *string `json:"listRule"`
+	ViewRule   *string `json:"viewRule"`
+	CreateRule *string `json:"createRule"`
+	UpdateRule *string `json:"updateRule"`
+	DeleteRule *string `json:"deleteRule"`
+
+	// DefaultSort is used by list requests without a sort parameter
+	DefaultSort string `json:"defaultSort"`
+	// DefaultFilter is added to list requests whose filter does not
+	// reference any of the fields it uses (e.g. hiding inactive products)
+	DefaultFilter string `json:"defaultFilter"`
+	// RequireIfMatch rejects record updates and deletes that do not send
+	// the ETag of the record version they apply to in If-Match
+	RequireIfMatch bool `json:"requireIfMatch"`
+}
+
+// Collection types: regular data collections, and auth collections whose
+// records can sign in with a password
+const (
+	CollectionTypeBase = "base"
+	CollectionTypeAuth = "auth"
+)
+
+// SuperusersCollectionName is the auth collection of the superusers, whose
+// records are admins: they bypass the API rules and manage the application
+const SuperusersCollectionName = "_superusers"
+
+// IsAuth reports whether the records of the collection can authenticate
+func (c *Collection) IsAuth() bool {
+	return c.Type == CollectionTypeAuth
+}
+
+// Rule returns a pointer to rule, for use in collection definitions
+func Rule(rule string) *string {
+	return &rule
+}
+
+// systemFields are present on every collection
+var systemFields = []Field{
+	{Name: "id", Type: FieldTypeText},
+	{Name: "created", Type: FieldTypeDate},
+	{Name: "updated", Type: FieldTypeDate},
+}
+
+// Field looks up a field, including the system fields, by its API name
+func (c *Collection) Field(name string) (Field, bool) {
+	for _, f := range systemFields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	for _, f := range c.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// AllFields returns the system fields followed by the collection fields
+func (c *Collection) AllFields() []Field {
+	fields := make([]Field, 0, len(systemFields)+len(c.Fields))
+	fields = append(fields, systemFields...)
+	return append(fields, c.Fields...)
+}
+
+// Clone returns a deep copy of the collection that can be modified without
+// affecting requests still using the registered instance
+func (c *Collection) Clone() *Collection {
+	clone := *c
+	clone.Fields = append([]Field{}, c.Fields...)
+	for i, f := range clone.Fields {
+		if f.MimeTypes != nil {
+			clone.Fields[i].MimeTypes = append([]string{}, f.MimeTypes...)
+		}
+		if f.Thumbs != nil {
+			clone.Fields[i].Thumbs = append([]string{}, f.Thumbs...)
+		}
+	}
+	clone.Indexes = append([]string{}, c.Indexes...)
+	for _, rule := range []**string{&clone.ListRule, &clone.ViewRule, &clone.CreateRule, &clone.UpdateRule, &clone.DeleteRule} {
+		if *rule != nil {
+			*rule = Rule(**rule)
+		}
+	}
+	return &clone
+}
+
+// The registry is replaced rather than mutated when collections change, so
+// handlers holding a *Collection keep a consistent schema for the request.
+var (
+	collectionRegistry   = map[string]*Collection{}
+	collectionRegistryMu sync.RWMutex
+)
+
+// RegisterCollection adds a collection to the registry, replacing any
+// collection with the same name
+func RegisterCollection(c *Collection) {
+	if c.Table == "" {
+		c.Table = c.Name
+	}
+	if c.Type == "" {
+		c.Type = CollectionTypeBase
+	}
+	if c.DefaultSort == "" {
+		c.DefaultSort = "-created"
+	}
+
+	collectionRegistryMu.Lock()
+	defer collectionRegistryMu.Unlock()
+	collectionRegistry[c.Name] = c
+}
+
+// UnregisterCollection removes a collection from the registry
+func UnregisterCollection(name string) {
+	collectionRegistryMu.Lock()
+	defer collectionRegistryMu.Unlock()
+	delete(collectionRegistry, name)
+}
+
+// FindCollection returns the registered collection with the given name
+func FindCollection(name string) (*Collection, bool) {
+	collectionRegistryMu.RLock()
+	defer collectionRegistryMu.RUnlock()
+	c, ok := collectionRegistry[name]
+	return c, ok
+}
+
+// Collections returns all registered collections sorted by name
+func Collections() []*Collection {
+	collectionRegistryMu.RLock()
+	defer collectionRegistryMu.RUnlock()
+	list := make([]*Collection, 0, len(collectionRegistry))
+	for _, c := range collectionRegistry {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// FindCollectionByID returns the collection with the given id, falling back
+// to a lookup by name
+func FindCollectionByID(id string) (*Collection, bool) {
+	for _, c := range Collections() {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return FindCollection(id)
+}
@@ -0,0 +1,761 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/VieShare/vieshare-gin/db"
+	uuid "github.com/google/uuid"
+)
+
+// Collection schemas are persisted in the _collections table, mirroring
+// PocketBase. The built-in collections registered in collections.go are
+// stored once, recorded in _migrations so that a collection deleted through
+// the API is not recreated on the next boot. Later changes to the metadata
+// tables are applied by systemMigrations, also recorded in _migrations.
+
+const collectionsTableSQL = `
+CREATE TABLE IF NOT EXISTS _collections (
+    id TEXT PRIMARY KEY,
+    created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+    name TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL DEFAULT 'base',
+    system BOOLEAN NOT NULL DEFAULT FALSE,
+    fields JSON NOT NULL DEFAULT '[]',
+    indexes JSON NOT NULL DEFAULT '[]',
+    options JSON NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE IF NOT EXISTS _migrations (
+    file TEXT PRIMARY KEY,
+    applied DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+// storedField is the _collections representation of a Field. Unlike the API
+// representation it keeps the backing column name.
+type storedField struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Column   string    `json:"column,omitempty"`
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required"`
+	Relation string    `json:"collection,omitempty"`
+
+	MaxSelect int      `json:"maxSelect,omitempty"`
+	MaxSize   int64    `json:"maxSize,omitempty"`
+	MimeTypes []string `json:"mimeTypes,omitempty"`
+	Thumbs    []string `json:"thumbs,omitempty"`
+}
+
+func newStoredField(f Field) storedField {
+	return storedField{
+		ID: f.ID, Name: f.Name, Column: f.Column, Type: f.Type, Required: f.Required, Relation: f.Relation,
+		MaxSelect: f.MaxSelect, MaxSize: f.MaxSize, MimeTypes: f.MimeTypes, Thumbs: f.Thumbs,
+	}
+}
+
+func (f storedField) field() Field {
+	return Field{
+		ID: f.ID, Name: f.Name, Column: f.Column, Type: f.Type, Required: f.Required, Relation: f.Relation,
+		MaxSelect: f.MaxSelect, MaxSize: f.MaxSize, MimeTypes: f.MimeTypes, Thumbs: f.Thumbs,
+	}
+}
+
+// collectionOptions holds the collection settings without a dedicated column
+type collectionOptions struct {
+	DefaultSort    string `json:"defaultSort,omitempty"`
+	DefaultFilter  string `json:"defaultFilter,omitempty"`
+	RequireIfMatch bool   `json:"requireIfMatch,omitempty"`
+}
+
+// systemMigrations upgrade the metadata tables of existing databases, in
+// order. Each one runs once.
+var systemMigrations = []struct {
+	file string
+	up   func(tx *sql.Tx) error
+}{
+	{"1_collection_rules", addCollectionRules},
+	{"2_file_fields", useFileFields},
+	{"3_file_thumbs", addFileThumbs},
+	{"4_updated_triggers", guardUpdatedTriggers},
+	{"5_soft_delete", addDeletedColumns},
+	{"6_audit_log", createAuditLog},
+	{"7_auth_collections", addPasswordFields},
+	{"8_params", createParams},
+	{"9_request_logs", createLogs},
+	{"10_sessions", createSessions},
+	{"11_tokens", createTokens},
+}
+
+// ruleColumns are the _collections columns holding the API rules
+var ruleColumns = []string{"listRule", "viewRule", "createRule", "updateRule", "deleteRule"}
+
+// addCollectionRules adds the API rule columns. Stored built-in collections
+// receive their default rules; other collections stay admin-only.
+func addCollectionRules(tx *sql.Tx) error {
+	for _, column := range ruleColumns {
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE _collections ADD COLUMN [%s] TEXT DEFAULT NULL", column)); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range Collections() {
+		_, err := tx.Exec("UPDATE _collections SET listRule = ?, viewRule = ?, createRule = ?, updateRule = ?, deleteRule = ? WHERE name = ?",
+			c.ListRule, c.ViewRule, c.CreateRule, c.UpdateRule, c.DeleteRule, c.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// useFileFields turns the image fields of stored built-in collections, which
+// used to hold the names of externally hosted files, into file fields. The
+// existing values are kept as file names.
+func useFileFields(tx *sql.Tx) error {
+	return updateStoredFields(tx, func(builtin Field, f *storedField) bool {
+		if builtin.Type != FieldTypeFile || f.Type == FieldTypeFile {
+			return false
+		}
+		builtin.ID, builtin.Column = f.ID, f.Column
+		*f = newStoredField(builtin)
+		return true
+	})
+}
+
+// addFileThumbs allows the default thumbnail sizes of the file fields of
+// stored built-in collections
+func addFileThumbs(tx *sql.Tx) error {
+	return updateStoredFields(tx, func(builtin Field, f *storedField) bool {
+		if builtin.Type != FieldTypeFile || f.Type != FieldTypeFile || f.Thumbs != nil {
+			return false
+		}
+		f.Thumbs = builtin.Thumbs
+		return true
+	})
+}
+
+// updatedTriggerSQL is the trigger stamping the updated column of records
+// changed by writes that do not set it, such as manual SQL. Record writes of
+// the API set it themselves, with the precision record ETags rely on.
+const updatedTriggerSQL = `CREATE TRIGGER [%[1]s]
+AFTER UPDATE ON [%[2]s] FOR EACH ROW WHEN NEW.updated IS OLD.updated
+BEGIN
+    UPDATE [%[2]s] SET updated = strftime('%%Y-%%m-%%d %%H:%%M:%%fZ', 'now') WHERE id = NEW.id;
+END`
+
+// guardUpdatedTriggers replaces the update_<table>_updated_at triggers of
+// databases created before they were guarded, which overwrote the updated
+// value of every write with a timestamp truncated to the second
+func guardUpdatedTriggers(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT name, tbl_name FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'update\_%\_updated\_at' ESCAPE '\'`)
+	if err != nil {
+		return err
+	}
+	var triggers [][2]string
+	for rows.Next() {
+		var name, table string
+		if err := rows.Scan(&name, &table); err != nil {
+			rows.Close()
+			return err
+		}
+		triggers = append(triggers, [2]string{name, table})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range triggers {
+		if _, err := tx.Exec(fmt.Sprintf("DROP TRIGGER [%s]", t[0])); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf(updatedTriggerSQL, t[0], t[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectionTables returns the existing tables of the built-in and stored
+// collections
+func collectionTables(tx *sql.Tx) ([]string, error) {
+	names := []string{}
+	for _, c := range Collections() {
+		names = append(names, c.Table)
+	}
+	rows, err := tx.Query("SELECT name FROM _collections ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tables := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		exists, err := tableExists(tx, name)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			tables = append(tables, name)
+		}
+	}
+	return tables, nil
+}
+
+// addDeletedColumns adds the deleted column, which marks the records moved
+// to the trash, to the tables of the built-in and stored collections. Their
+// UNIQUE constraints, which would keep the values of trashed records from
+// being reused, are replaced with unique indexes ignoring the trash and
+// stored with the indexes of their collection.
+func addDeletedColumns(tx *sql.Tx) error {
+	// The search triggers reference the rebuilt tables, they are recreated
+	// on startup
+	if err := dropProductsSearchTriggers(tx); err != nil {
+		return err
+	}
+
+	tables, err := collectionTables(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		hasColumn, err := columnExists(tx, table, "deleted")
+		if err != nil {
+			return err
+		}
+		if !hasColumn {
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE [%s] ADD COLUMN [deleted] DATETIME DEFAULT NULL", table)); err != nil {
+				return err
+			}
+		}
+
+		created, err := replaceUniqueConstraints(tx, table)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
+		if len(created) == 0 {
+			continue
+		}
+		err = updateStoredIndexes(tx, table, func(indexes []string) []string {
+			return append(indexes, created...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStoredIndexes applies update to the stored indexes of a collection,
+// if it is stored
+func updateStoredIndexes(tx *sql.Tx, name string, update func(indexes []string) []string) error {
+	var stored string
+	err := tx.QueryRow("SELECT indexes FROM _collections WHERE name = ?", name).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var indexes []string
+	if err := json.Unmarshal([]byte(stored), &indexes); err != nil {
+		return fmt.Errorf("collection %s: invalid indexes: %w", name, err)
+	}
+	encoded, err := json.Marshal(update(indexes))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE _collections SET indexes = ? WHERE name = ?", string(encoded), name)
+	return err
+}
+
+// addPasswordFields adds the password fields of the built-in auth
+// collections to their tables and stored fields, and stores their type. The
+// tables also get the token key column, with a key for every record.
+func addPasswordFields(tx *sql.Tx) error {
+	for _, c := range Collections() {
+		if !c.IsAuth() {
+			continue
+		}
+		var passwords []Field
+		for _, f := range c.Fields {
+			if f.Type == FieldTypePassword {
+				passwords = append(passwords, f)
+			}
+		}
+
+		exists, err := tableExists(tx, c.Table)
+		if err != nil {
+			return err
+		}
+		for _, f := range passwords {
+			if !exists {
+				break
+			}
+			hasColumn, err := columnExists(tx, c.Table, f.ColumnName())
+			if err != nil {
+				return err
+			}
+			if hasColumn {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE [%s] ADD COLUMN %s", c.Table, columnDefinition(f))); err != nil {
+				return err
+			}
+		}
+		if exists {
+			if err := addTokenKeyColumn(tx, c.Table); err != nil {
+				return err
+			}
+		}
+
+		var fields string
+		err = tx.QueryRow("SELECT fields FROM _collections WHERE name = ?", c.Name).Scan(&fields)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var stored []storedField
+		if err := json.Unmarshal([]byte(fields), &stored); err != nil {
+			return fmt.Errorf("collection %s: invalid fields: %w", c.Name, err)
+		}
+		for _, f := range passwords {
+			known := false
+			for _, sf := range stored {
+				known = known || sf.Name == f.Name
+			}
+			if !known {
+				f.ID = NewCollectionID(string(f.Type))
+				stored = append(stored, newStoredField(f))
+			}
+		}
+		encoded, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE _collections SET type = ?, fields = ? WHERE name = ?", c.Type, string(encoded), c.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTokenKeyColumn adds the token key column to the table of an auth
+// collection and gives every record its own key
+func addTokenKeyColumn(tx *sql.Tx, table string) error {
+	hasColumn, err := columnExists(tx, table, TokenKeyColumn)
+	if err != nil {
+		return err
+	}
+	if !hasColumn {
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE [%s] ADD COLUMN [%s] TEXT", table, TokenKeyColumn)); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(fmt.Sprintf("UPDATE [%s] SET [%s] = lower(hex(randomblob(25))) WHERE [%[2]s] IS NULL", table, TokenKeyColumn))
+	return err
+}
+
+// updateStoredFields applies update to the stored fields of the built-in
+// collections that are defined with the same name. update reports whether it
+// changed the field.
+func updateStoredFields(tx *sql.Tx, update func(builtin Field, f *storedField) bool) error {
+	for _, c := range Collections() {
+		var fields string
+		err := tx.QueryRow("SELECT fields FROM _collections WHERE name = ?", c.Name).Scan(&fields)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var stored []storedField
+		if err := json.Unmarshal([]byte(fields), &stored); err != nil {
+			return fmt.Errorf("collection %s: invalid fields: %w", c.Name, err)
+		}
+		changed := false
+		for i := range stored {
+			if builtin, ok := c.Field(stored[i].Name); ok && update(builtin, &stored[i]) {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		encoded, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE _collections SET fields = ? WHERE name = ?", string(encoded), c.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewCollectionID returns a random id for a collection or field
+func NewCollectionID(prefix string) string {
+	return prefix + strings.ReplaceAll(uuid.New().String(), "-", "")[:15-len(prefix)]
+}
+
+// InitCollections checks that SQLite supports the products search, loads the
+// collections and settings with LoadCollections and builds the search index
+func InitCollections() error {
+	if err := checkSearchModule(db.GetDB().Db); err != nil {
+		return err
+	}
+	if err := LoadCollections(); err != nil {
+		return err
+	}
+	return RefreshProductsSearch()
+}
+
+// LoadCollections creates the metadata tables, stores any built-in collection
+// that was never stored before and loads the registry from _collections and
+// the settings from _params. Unlike InitCollections it leaves the products
+// search index alone, so it also runs with SQLite builds lacking FTS5.
+func LoadCollections() error {
+	conn := db.GetDB().Db
+
+	if _, err := conn.Exec(collectionsTableSQL); err != nil {
+		return err
+	}
+
+	for _, m := range systemMigrations {
+		if err := applyMigration(conn, m.file, m.up); err != nil {
+			return fmt.Errorf("migration %s: %w", m.file, err)
+		}
+	}
+
+	for _, c := range Collections() {
+		if err := seedCollection(conn, c); err != nil {
+			return fmt.Errorf("collection %s: %w", c.Name, err)
+		}
+	}
+
+	if err := loadCollections(conn); err != nil {
+		return err
+	}
+	return loadSettings(conn)
+}
+
+// applyMigration runs up unless file is already recorded in _migrations
+func applyMigration(conn *sql.DB, file string, up func(tx *sql.Tx) error) error {
+	var applied bool
+	if err := conn.QueryRow("SELECT EXISTS(SELECT 1 FROM _migrations WHERE file = ?)", file).Scan(&applied); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	return migrate(func(tx *sql.Tx) error {
+		if err := up(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO _migrations (file) VALUES (?)", file)
+		if err == nil {
+			log.Printf("Applied migration %s", file)
+		}
+		return err
+	})
+}
+
+// seedCollection stores a built-in collection, creating its table when the
+// schema file did not
+func seedCollection(conn *sql.DB, c *Collection) error {
+	c = c.Clone()
+	if c.ID == "" {
+		c.ID = NewCollectionID("pbc_")
+	}
+	for i := range c.Fields {
+		if c.Fields[i].ID == "" {
+			c.Fields[i].ID = NewCollectionID(string(c.Fields[i].Type))
+		}
+	}
+
+	return applyMigration(conn, "builtin_"+c.Name, func(tx *sql.Tx) error {
+		exists, err := tableExists(tx, c.Table)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			indexes, err := tableIndexes(tx, c.Table)
+			if err != nil {
+				return err
+			}
+			c.Indexes = indexes
+			if err := useCollectionID(tx, c.Table, c.ID); err != nil {
+				return err
+			}
+		} else {
+			if err := createTable(tx, c); err != nil {
+				return err
+			}
+			if err := createIndexes(tx, c.Indexes); err != nil {
+				return err
+			}
+		}
+
+		return saveCollection(tx, c)
+	})
+}
+
+// loadCollections replaces the registry with the stored collections
+func loadCollections(conn *sql.DB) error {
+	rows, err := conn.Query("SELECT id, name, type, system, fields, indexes, options, listRule, viewRule, createRule, updateRule, deleteRule FROM _collections")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var loaded []*Collection
+	for rows.Next() {
+		var c Collection
+		var fields, indexes, options string
+		var rules [5]sql.NullString
+		if err := rows.Scan(&c.ID, &c.Name, &c.Type, &c.System, &fields, &indexes, &options,
+			&rules[0], &rules[1], &rules[2], &rules[3], &rules[4]); err != nil {
+			return err
+		}
+
+		var stored []storedField
+		if err := json.Unmarshal([]byte(fields), &stored); err != nil {
+			return fmt.Errorf("collection %s: invalid fields: %w", c.Name, err)
+		}
+		for _, f := range stored {
+			c.Fields = append(c.Fields, f.field())
+		}
+
+		if err := json.Unmarshal([]byte(indexes), &c.Indexes); err != nil {
+			return fmt.Errorf("collection %s: invalid indexes: %w", c.Name, err)
+		}
+
+		var opts collectionOptions
+		if err := json.Unmarshal([]byte(options), &opts); err != nil {
+			return fmt.Errorf("collection %s: invalid options: %w", c.Name, err)
+		}
+		c.DefaultSort, c.DefaultFilter, c.RequireIfMatch = opts.DefaultSort, opts.DefaultFilter, opts.RequireIfMatch
+
+		for i, rule := range []**string{&c.ListRule, &c.ViewRule, &c.CreateRule, &c.UpdateRule, &c.DeleteRule} {
+			if rules[i].Valid {
+				*rule = Rule(rules[i].String)
+			}
+		}
+
+		loaded = append(loaded, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	collectionRegistryMu.Lock()
+	collectionRegistry = map[string]*Collection{}
+	collectionRegistryMu.Unlock()
+
+	for _, c := range loaded {
+		RegisterCollection(c)
+	}
+	return nil
+}
+
+// saveCollection inserts or updates the metadata row of a collection
+func saveCollection(tx *sql.Tx, c *Collection) error {
+	stored := make([]storedField, len(c.Fields))
+	for i, f := range c.Fields {
+		stored[i] = newStoredField(f)
+	}
+	fields, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	indexes := c.Indexes
+	if indexes == nil {
+		indexes = []string{}
+	}
+	indexesJSON, err := json.Marshal(indexes)
+	if err != nil {
+		return err
+	}
+
+	options, err := json.Marshal(collectionOptions{DefaultSort: c.DefaultSort, DefaultFilter: c.DefaultFilter, RequireIfMatch: c.RequireIfMatch})
+	if err != nil {
+		return err
+	}
+
+	collectionType := c.Type
+	if collectionType == "" {
+		collectionType = CollectionTypeBase
+	}
+
+	_, err = tx.Exec(`INSERT INTO _collections (id, name, type, system, fields, indexes, options,
+			listRule, viewRule, createRule, updateRule, deleteRule)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name, type = excluded.type, system = excluded.system,
+			fields = excluded.fields, indexes = excluded.indexes, options = excluded.options,
+			listRule = excluded.listRule, viewRule = excluded.viewRule, createRule = excluded.createRule,
+			updateRule = excluded.updateRule, deleteRule = excluded.deleteRule,
+			updated = CURRENT_TIMESTAMP`,
+		c.ID, c.Name, collectionType, c.System, string(fields), string(indexesJSON), string(options),
+		c.ListRule, c.ViewRule, c.CreateRule, c.UpdateRule, c.DeleteRule)
+	return err
+}
+
+// CreateCollection creates the table of a new collection, stores its
+// metadata and registers it
+func CreateCollection(c *Collection) error {
+	c.Table = c.Name
+	err := migrate(func(tx *sql.Tx) error {
+		if err := createTable(tx, c); err != nil {
+			return err
+		}
+		if err := createIndexes(tx, c.Indexes); err != nil {
+			return err
+		}
+		return saveCollection(tx, c)
+	})
+	if err != nil {
+		return err
+	}
+
+	RegisterCollection(c)
+	return nil
+}
+
+// UpdateCollection migrates the table of old to the schema of updated. Fields
+// are matched by id: renamed fields keep their data, removed fields are
+// dropped and type changes rebuild the table. Relation fields of other
+// collections follow a collection rename.
+func UpdateCollection(old, updated *Collection) error {
+	updated.Table = updated.Name
+
+	var related []*Collection
+	err := migrate(func(tx *sql.Tx) error {
+		// The search index triggers are recreated for the new schema by
+		// RefreshProductsSearch
+		if err := dropProductsSearchTriggers(tx); err != nil {
+			return err
+		}
+
+		// Indexes are recreated from the new definition once the columns
+		// have been migrated
+		if err := dropIndexes(tx, old.Indexes); err != nil {
+			return err
+		}
+
+		if old.Table != updated.Table {
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE [%s] RENAME TO [%s]", old.Table, updated.Table)); err != nil {
+				return err
+			}
+			query := fmt.Sprintf("UPDATE [%s] SET [collection_name] = ?", updated.Table)
+			if _, err := tx.Exec(query, updated.Name); err != nil {
+				return err
+			}
+			for i, index := range updated.Indexes {
+				updated.Indexes[i] = RenameIndexTable(index, old.Table, updated.Table)
+			}
+			for i, f := range updated.Fields {
+				if f.Type == FieldTypeRelation && f.Relation == old.Name {
+					updated.Fields[i].Relation = updated.Name
+				}
+			}
+		}
+
+		if err := migrateFields(tx, old, updated); err != nil {
+			return err
+		}
+		if err := createIndexes(tx, updated.Indexes); err != nil {
+			return err
+		}
+		if err := saveCollection(tx, updated); err != nil {
+			return err
+		}
+
+		if old.Name == updated.Name {
+			return nil
+		}
+		for _, other := range Collections() {
+			if other.Name == old.Name {
+				continue
+			}
+			changed := other.Clone()
+			renamed := false
+			for i, f := range changed.Fields {
+				if f.Type == FieldTypeRelation && f.Relation == old.Name {
+					changed.Fields[i].Relation = updated.Name
+					renamed = true
+				}
+			}
+			if !renamed {
+				continue
+			}
+			if err := saveCollection(tx, changed); err != nil {
+				return err
+			}
+			related = append(related, changed)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if old.Name != updated.Name {
+		UnregisterCollection(old.Name)
+	}
+	RegisterCollection(updated)
+	for _, c := range related {
+		RegisterCollection(c)
+	}
+	return refreshProductsSearch()
+}
+
+// DeleteCollection drops the table of a collection and its metadata
+func DeleteCollection(c *Collection) error {
+	err := migrate(func(tx *sql.Tx) error {
+		if err := dropProductsSearchTriggers(tx); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS [%s]", c.Table)); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM _collections WHERE id = ?", c.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	UnregisterCollection(c.Name)
+	return refreshProductsSearch()
+}
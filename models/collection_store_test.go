@@ -0,0 +1,43 @@
+package models
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/VieShare/vieshare-gin/db"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	builtinCollections     []*Collection
+	builtinCollectionsOnce sync.Once
+)
+
+// openTestDB initializes a new database from db/pocketbase_schema.sql, with
+// the registry reset to the built-in collections, and loads the collections
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	builtinCollectionsOnce.Do(func() {
+		for _, c := range Collections() {
+			builtinCollections = append(builtinCollections, c.Clone())
+		}
+	})
+	collectionRegistryMu.Lock()
+	collectionRegistry = map[string]*Collection{}
+	collectionRegistryMu.Unlock()
+	for _, c := range builtinCollections {
+		RegisterCollection(c.Clone())
+	}
+
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "app.db"))
+	t.Chdir("..")
+	db.Init()
+	conn := db.GetDB().Db
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, LoadCollections())
+	return conn
+}
@@ -0,0 +1,215 @@
+package models
+
+// Schema definitions for the built-in collections created by
+// db/pocketbase_schema.sql, or by InitCollections for those it lacks. They
+// seed the _collections table on first boot; afterwards the stored metadata
+// is authoritative. Rules left nil are admin-only.
+
+// ownerUpdateRule lets the user of a record update it without handing it
+// over to another user
+const ownerUpdateRule = "user = @request.auth.id && (@request.body.user:isset = false || @request.body.user = @request.auth.id)"
+
+// imageMimeTypes are accepted by the image fields of the built-in collections
+var imageMimeTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+func init() {
+	RegisterCollection(&Collection{
+		Name:   SuperusersCollectionName,
+		Type:   CollectionTypeAuth,
+		System: true,
+		Fields: []Field{
+			{Name: "email", Type: FieldTypeEmail, Required: true},
+			{Name: "password", Type: FieldTypePassword, Required: true},
+		},
+		Indexes: []string{"CREATE UNIQUE INDEX idx__superusers_email ON _superusers (email COLLATE NOCASE) WHERE deleted IS NULL"},
+	})
+
+	RegisterCollection(&Collection{
+		Name:       "users",
+		Type:       CollectionTypeAuth,
+		System:     true,
+		ListRule:   Rule("id = @request.auth.id"),
+		ViewRule:   Rule("id = @request.auth.id"),
+		CreateRule: Rule(""),
+		UpdateRule: Rule("id = @request.auth.id"),
+		DeleteRule: Rule("id = @request.auth.id"),
+		Fields: []Field{
+			{Name: "email", Type: FieldTypeEmail, Required: true},
+			{Name: "emailVisibility", Column: "email_visibility", Type: FieldTypeBool},
+			{Name: "username", Type: FieldTypeText, Required: true},
+			{Name: "name", Type: FieldTypeText},
+			{Name: "avatar", Type: FieldTypeFile, MaxSelect: 1, MimeTypes: imageMimeTypes, Thumbs: []string{"100x100"}},
+			{Name: "verified", Type: FieldTypeBool},
+			{Name: "password", Type: FieldTypePassword, Required: true},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:        "categories",
+		DefaultSort: "name",
+		ListRule:    Rule(""),
+		ViewRule:    Rule(""),
+		Fields: []Field{
+			{Name: "name", Type: FieldTypeText, Required: true},
+			{Name: "slug", Type: FieldTypeText, Required: true},
+			{Name: "description", Type: FieldTypeText},
+			{Name: "image", Type: FieldTypeFile, MaxSelect: 1, MimeTypes: imageMimeTypes, Thumbs: []string{"100x100", "400x0"}},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:        "subcategories",
+		DefaultSort: "name",
+		ListRule:    Rule(""),
+		ViewRule:    Rule(""),
+		Fields: []Field{
+			{Name: "name", Type: FieldTypeText, Required: true},
+			{Name: "slug", Type: FieldTypeText, Required: true},
+			{Name: "description", Type: FieldTypeText},
+			{Name: "category", Type: FieldTypeRelation, Required: true, Relation: "categories"},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:          "stores",
+		DefaultFilter: "active = true",
+		ListRule:      Rule(""),
+		ViewRule:      Rule(""),
+		CreateRule:    Rule(`@request.auth.id != "" && user = @request.auth.id`),
+		UpdateRule:    Rule(ownerUpdateRule),
+		DeleteRule:    Rule("user = @request.auth.id"),
+		Fields: []Field{
+			{Name: "name", Type: FieldTypeText, Required: true},
+			{Name: "slug", Type: FieldTypeText, Required: true},
+			{Name: "description", Type: FieldTypeText},
+			{Name: "user", Type: FieldTypeRelation, Required: true, Relation: "users"},
+			{Name: "plan", Type: FieldTypeText},
+			{Name: "plan_ends_at", Type: FieldTypeDate},
+			{Name: "cancel_plan_at_end", Type: FieldTypeBool},
+			{Name: "product_limit", Type: FieldTypeNumber},
+			{Name: "tag_limit", Type: FieldTypeNumber},
+			{Name: "variant_limit", Type: FieldTypeNumber},
+			{Name: "active", Type: FieldTypeBool},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:          "products",
+		DefaultFilter: "active = true",
+		ListRule:      Rule(""),
+		ViewRule:      Rule(""),
+		CreateRule:    Rule("store.user = @request.auth.id"),
+		UpdateRule:    Rule("store.user = @request.auth.id"),
+		DeleteRule:    Rule("store.user = @request.auth.id"),
+		Fields: []Field{
+			{Name: "name", Type: FieldTypeText, Required: true},
+			{Name: "description", Type: FieldTypeText},
+			{Name: "images", Type: FieldTypeFile, MaxSelect: 10, MimeTypes: imageMimeTypes, Thumbs: []string{"100x100", "300x300", "600x0"}},
+			{Name: "category", Type: FieldTypeRelation, Required: true, Relation: "categories"},
+			{Name: "subcategory", Type: FieldTypeRelation, Relation: "subcategories"},
+			{Name: "price", Type: FieldTypeText, Required: true},
+			{Name: "inventory", Type: FieldTypeNumber},
+			{Name: "rating", Type: FieldTypeNumber},
+			{Name: "store", Type: FieldTypeRelation, Required: true, Relation: "stores"},
+			{Name: "active", Type: FieldTypeBool},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:       "carts",
+		ListRule:   Rule("user = @request.auth.id"),
+		ViewRule:   Rule("user = @request.auth.id"),
+		CreateRule: Rule(`@request.auth.id != "" && user = @request.auth.id`),
+		UpdateRule: Rule(ownerUpdateRule),
+		DeleteRule: Rule("user = @request.auth.id"),
+		Fields: []Field{
+			{Name: "user", Type: FieldTypeRelation, Relation: "users"},
+			{Name: "session_id", Type: FieldTypeText},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:       "cart_items",
+		ListRule:   Rule("cart.user = @request.auth.id"),
+		ViewRule:   Rule("cart.user = @request.auth.id"),
+		CreateRule: Rule("cart.user = @request.auth.id"),
+		UpdateRule: Rule("cart.user = @request.auth.id"),
+		DeleteRule: Rule("cart.user = @request.auth.id"),
+		Fields: []Field{
+			{Name: "cart", Type: FieldTypeRelation, Required: true, Relation: "carts"},
+			{Name: "product", Type: FieldTypeRelation, Required: true, Relation: "products"},
+			{Name: "quantity", Type: FieldTypeNumber, Required: true},
+			{Name: "subcategory", Type: FieldTypeRelation, Relation: "subcategories"},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:       "addresses",
+		ListRule:   Rule("user = @request.auth.id"),
+		ViewRule:   Rule("user = @request.auth.id"),
+		CreateRule: Rule(`@request.auth.id != "" && user = @request.auth.id`),
+		UpdateRule: Rule(ownerUpdateRule),
+		DeleteRule: Rule("user = @request.auth.id"),
+		Fields: []Field{
+			{Name: "line1", Type: FieldTypeText, Required: true},
+			{Name: "line2", Type: FieldTypeText},
+			{Name: "city", Type: FieldTypeText, Required: true},
+			{Name: "state", Type: FieldTypeText, Required: true},
+			{Name: "postal_code", Type: FieldTypeText, Required: true},
+			{Name: "country", Type: FieldTypeText, Required: true},
+			{Name: "user", Type: FieldTypeRelation, Required: true, Relation: "users"},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:       "orders",
+		ListRule:   Rule("user = @request.auth.id || store.user = @request.auth.id"),
+		ViewRule:   Rule("user = @request.auth.id || store.user = @request.auth.id"),
+		CreateRule: Rule(`@request.auth.id != "" && user = @request.auth.id`),
+		UpdateRule: Rule("store.user = @request.auth.id"),
+		Fields: []Field{
+			{Name: "user", Type: FieldTypeRelation, Relation: "users"},
+			{Name: "store", Type: FieldTypeRelation, Required: true, Relation: "stores"},
+			{Name: "items", Type: FieldTypeJSON},
+			{Name: "quantity", Type: FieldTypeNumber},
+			{Name: "amount", Type: FieldTypeText, Required: true},
+			{Name: "status", Type: FieldTypeText},
+			{Name: "name", Type: FieldTypeText, Required: true},
+			{Name: "email", Type: FieldTypeEmail, Required: true},
+			{Name: "address", Type: FieldTypeRelation, Required: true, Relation: "addresses"},
+			{Name: "notes", Type: FieldTypeText},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:       "customers",
+		ListRule:   Rule("store.user = @request.auth.id"),
+		ViewRule:   Rule("store.user = @request.auth.id"),
+		CreateRule: Rule("store.user = @request.auth.id"),
+		UpdateRule: Rule("store.user = @request.auth.id"),
+		DeleteRule: Rule("store.user = @request.auth.id"),
+		Fields: []Field{
+			{Name: "name", Type: FieldTypeText},
+			{Name: "email", Type: FieldTypeEmail, Required: true},
+			{Name: "store", Type: FieldTypeRelation, Required: true, Relation: "stores"},
+			{Name: "total_orders", Type: FieldTypeNumber},
+			{Name: "total_spent", Type: FieldTypeText},
+		},
+	})
+
+	RegisterCollection(&Collection{
+		Name:       "notifications",
+		ListRule:   Rule("user = @request.auth.id"),
+		ViewRule:   Rule("user = @request.auth.id"),
+		UpdateRule: Rule(ownerUpdateRule),
+		DeleteRule: Rule("user = @request.auth.id"),
+		Fields: []Field{
+			{Name: "email", Type: FieldTypeEmail, Required: true},
+			{Name: "token", Type: FieldTypeText, Required: true},
+			{Name: "user", Type: FieldTypeRelation, Relation: "users"},
+			{Name: "communication", Type: FieldTypeBool},
+			{Name: "newsletter", Type: FieldTypeBool},
+			{Name: "marketing", Type: FieldTypeBool},
+		},
+	})
+}
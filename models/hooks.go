@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// Record lifecycle hooks.
+//
+// Handlers are registered for some collections, or for all of them when no
+// collection is given:
+//
+//	models.OnRecordBeforeCreate.Add(func(e *models.RecordEvent) error {
+//		if e.Data["slug"] == nil {
+//			e.Data["slug"] = slugify(e.Data["name"])
+//		}
+//		return nil
+//	}, "categories", "stores")
+//
+// Before hooks run inside the transaction of the operation, in registration
+// order. They may change e.Data and abort the operation by returning an
+// error: FieldErrors produce a validation response and other errors a 400
+// response with their message. After hooks run once the transaction has
+// committed; their errors are logged.
+
+// RecordEvent describes the record operation passed to hook handlers
+type RecordEvent struct {
+	Collection *Collection
+
+	// Record is the stored record: the current record in before update and
+	// delete hooks, the result in after hooks and nil in OnRecordBeforeCreate
+	Record Record
+
+	// Data holds the submitted values of create and update operations.
+	// Before hooks may modify it.
+	Data map[string]interface{}
+
+	// Tx is the transaction of the operation in before hooks and nil in
+	// after hooks
+	Tx *sql.Tx
+
+	// Auth is the authenticated record, a superuser for admins and nil for
+	// guests and ADMIN_TOKEN requests
+	Auth  Record
+	Admin bool
+}
+
+// RecordHandler handles a record event
+type RecordHandler func(e *RecordEvent) error
+
+type recordHandler struct {
+	id          int
+	collections []string
+	fn          RecordHandler
+}
+
+// RecordHook is the list of handlers of one lifecycle event
+type RecordHook struct {
+	mu       sync.RWMutex
+	handlers []recordHandler
+	nextID   int
+}
+
+// Record hooks, see RecordEvent
+var (
+	OnRecordBeforeCreate = &RecordHook{}
+	OnRecordAfterCreate  = &RecordHook{}
+	OnRecordBeforeUpdate = &RecordHook{}
+	OnRecordAfterUpdate  = &RecordHook{}
+	OnRecordBeforeDelete = &RecordHook{}
+	OnRecordAfterDelete  = &RecordHook{}
+)
+
+// Add registers fn for the records of the given collections, or of every
+// collection when none is given, and returns a function removing it
+func (h *RecordHook) Add(fn RecordHandler, collections ...string) (remove func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	id := h.nextID
+	h.handlers = append(h.handlers, recordHandler{id: id, collections: collections, fn: fn})
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for i, handler := range h.handlers {
+			if handler.id == id {
+				h.handlers = append(h.handlers[:i:i], h.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Trigger runs the handlers registered for the collection of e, stopping at
+// the first error
+func (h *RecordHook) Trigger(e *RecordEvent) error {
+	h.mu.RLock()
+	handlers := append([]recordHandler(nil), h.handlers...)
+	h.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if !handler.matches(e.Collection) {
+			continue
+		}
+		if err := handler.fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h recordHandler) matches(collection *Collection) bool {
+	if len(h.collections) == 0 {
+		return true
+	}
+	for _, name := range h.collections {
+		if name == collection.Name || name == collection.ID {
+			return true
+		}
+	}
+	return false
+}
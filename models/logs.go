@@ -0,0 +1,63 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Request logs.
+//
+// Every /api request adds an entry to _logs once its response is written,
+// with who made it and how long it took. Entries are kept for the number of
+// days of the logs settings.
+
+const logsTableSQL = `
+CREATE TABLE IF NOT EXISTS _logs (
+    id TEXT PRIMARY KEY,
+    created DATETIME NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    method TEXT NOT NULL,
+    url TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    duration REAL NOT NULL DEFAULT 0,
+    remote_ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    referer TEXT NOT NULL DEFAULT '',
+    auth TEXT NOT NULL DEFAULT '',
+    auth_id TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_logs_created ON _logs (created);`
+
+// Request log auth values besides the name of an auth collection
+const (
+	LogAuthGuest = "guest"
+	LogAuthAdmin = "admin"
+)
+
+// LogEntry is a logged request
+type LogEntry struct {
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	RequestID string    `json:"requestId"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status"`
+	// Duration is in milliseconds
+	Duration  float64 `json:"duration"`
+	RemoteIP  string  `json:"remoteIp"`
+	UserAgent string  `json:"userAgent"`
+	Referer   string  `json:"referer"`
+	// Auth is "guest", "admin" for the ADMIN_TOKEN or the collection of the
+	// authenticated record
+	Auth   string `json:"auth"`
+	AuthID string `json:"authId"`
+	Error  string `json:"error"`
+}
+
+// createLogs creates the request log table
+func createLogs(tx *sql.Tx) error {
+	_, err := tx.Exec(logsTableSQL)
+	return err
+}
@@ -0,0 +1,658 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/VieShare/vieshare-gin/db"
+)
+
+// SchemaError reports a schema change that cannot be applied to the
+// existing table or data
+type SchemaError struct {
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return e.Message
+}
+
+// schemaMu serializes schema migrations
+var schemaMu sync.Mutex
+
+// migrate runs fn in a transaction on a dedicated connection with foreign key
+// enforcement disabled, as required for SQLite table rebuilds
+// (https://www.sqlite.org/lang_altertable.html#otheralter). Enforcement is
+// restored before the connection returns to the pool.
+func migrate(fn func(tx *sql.Tx) error) error {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+
+	ctx := context.Background()
+	conn, err := db.GetDB().Db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var foreignKeys bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return err
+	}
+	if foreignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func tableExists(tx *sql.Tx, table string) (bool, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", table).Scan(&exists)
+	return exists, err
+}
+
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", table, column).Scan(&exists)
+	return exists, err
+}
+
+// tableIndexes returns the CREATE INDEX statements of the explicit indexes of
+// a table. Indexes created implicitly by UNIQUE constraints have no SQL and
+// are part of the table definition instead.
+func tableIndexes(tx *sql.Tx, table string) ([]string, error) {
+	return schemaSQL(tx, "index", table)
+}
+
+func schemaSQL(tx *sql.Tx, kind, table string) ([]string, error) {
+	rows, err := tx.Query("SELECT sql FROM sqlite_master WHERE type = ? AND tbl_name = ? AND sql IS NOT NULL ORDER BY name", kind, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statements := []string{}
+	for rows.Next() {
+		var statement string
+		if err := rows.Scan(&statement); err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	return statements, rows.Err()
+}
+
+// columnDefinition returns the column definition of a field. Required is
+// validated by the record API rather than with NOT NULL so that fields can be
+// added to tables that already hold records.
+func columnDefinition(f Field) string {
+	def := "[" + f.ColumnName() + "] "
+	switch f.Type {
+	case FieldTypeNumber:
+		def += "NUMERIC"
+	case FieldTypeBool:
+		def += "BOOLEAN DEFAULT FALSE"
+	case FieldTypeDate:
+		def += "DATETIME"
+	case FieldTypeJSON:
+		def += "JSON"
+	case FieldTypeFile:
+		if f.IsMultiple() {
+			def += "JSON"
+		} else {
+			def += "TEXT"
+		}
+	case FieldTypeRelation:
+		def += "TEXT " + referencesClause(f.Relation, "SET NULL")
+	default:
+		def += "TEXT"
+	}
+	return def
+}
+
+// referencesClause returns the foreign key clause of a relation column
+func referencesClause(target, onDelete string) string {
+	return fmt.Sprintf("REFERENCES [%s]([id]) ON DELETE %s", target, onDelete)
+}
+
+func createTableSQL(table string, c *Collection) string {
+	definitions := make([]string, len(c.Fields))
+	for i, f := range c.Fields {
+		definitions[i] = columnDefinition(f)
+	}
+	return tableSQL(table, c, definitions)
+}
+
+// tableSQL returns the CREATE TABLE statement of a collection table with the
+// system columns followed by the given column definitions and table
+// constraints
+func tableSQL(table string, c *Collection, definitions []string) string {
+	columns := []string{
+		"[id] TEXT PRIMARY KEY",
+		"[created] DATETIME DEFAULT CURRENT_TIMESTAMP",
+		"[updated] DATETIME DEFAULT CURRENT_TIMESTAMP",
+		"[deleted] DATETIME DEFAULT NULL",
+		fmt.Sprintf("[collection_id] TEXT DEFAULT '%s'", c.ID),
+		fmt.Sprintf("[collection_name] TEXT DEFAULT '%s'", c.Name),
+	}
+	if c.IsAuth() {
+		columns = append(columns, "["+TokenKeyColumn+"] TEXT")
+	}
+	columns = append(columns, definitions...)
+	return fmt.Sprintf("CREATE TABLE [%s] (\n    %s\n)", table, strings.Join(columns, ",\n    "))
+}
+
+// tableColumn is a column of an existing table with the constraints a
+// rebuild carries over
+type tableColumn struct {
+	name         string
+	declaredType string
+	notNull      bool
+	defaultValue sql.NullString
+
+	// references is the table referenced by the foreign key of the column,
+	// "" without one
+	references string
+	onDelete   string
+	onUpdate   string
+}
+
+// definition returns the column definition recreating the column
+func (col tableColumn) definition() string {
+	def := "[" + col.name + "]"
+	if col.declaredType != "" {
+		def += " " + col.declaredType
+	}
+	if col.notNull {
+		def += " NOT NULL"
+	}
+	if col.defaultValue.Valid {
+		def += " DEFAULT (" + col.defaultValue.String + ")"
+	}
+	if col.references != "" {
+		def += " " + referencesClause(col.references, col.onDelete)
+		if col.onUpdate != "NO ACTION" {
+			def += " ON UPDATE " + col.onUpdate
+		}
+	}
+	return def
+}
+
+// tableColumns returns the columns of a table by lower-cased name
+func tableColumns(tx *sql.Tx, table string) (map[string]tableColumn, error) {
+	rows, err := tx.Query("SELECT name, type, [notnull], dflt_value FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]tableColumn{}
+	for rows.Next() {
+		var col tableColumn
+		if err := rows.Scan(&col.name, &col.declaredType, &col.notNull, &col.defaultValue); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		columns[strings.ToLower(col.name)] = col
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(`SELECT "table", "from", on_update, on_delete FROM pragma_foreign_key_list(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var target, from, onUpdate, onDelete string
+		if err := rows.Scan(&target, &from, &onUpdate, &onDelete); err != nil {
+			return nil, err
+		}
+		if col, ok := columns[strings.ToLower(from)]; ok {
+			col.references, col.onUpdate, col.onDelete = target, strings.ToUpper(onUpdate), strings.ToUpper(onDelete)
+			columns[strings.ToLower(from)] = col
+		}
+	}
+	return columns, rows.Err()
+}
+
+// uniqueConstraints returns the column lists of the UNIQUE constraints of a
+// table, which are part of its definition rather than explicit indexes
+func uniqueConstraints(tx *sql.Tx, table string) ([][]string, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_index_list(?) WHERE origin = 'u' ORDER BY name", table)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		indexes = append(indexes, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var constraints [][]string
+	for _, index := range indexes {
+		rows, err := tx.Query("SELECT name FROM pragma_index_info(?) ORDER BY seqno", index)
+		if err != nil {
+			return nil, err
+		}
+		var columns []string
+		for rows.Next() {
+			var column string
+			if err := rows.Scan(&column); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			columns = append(columns, column)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, columns)
+	}
+	return constraints, nil
+}
+
+// unsupportedConstraintRegex matches the parts of a table definition that
+// tableColumns and uniqueConstraints do not report, so that a rebuild would
+// drop them
+var unsupportedConstraintRegex = regexp.MustCompile(`(?i)\b(CHECK|COLLATE|GENERATED|AUTOINCREMENT)\b`)
+
+// checkRebuildable fails for tables whose definition has constraints that
+// rebuildTable cannot carry over
+func checkRebuildable(tx *sql.Tx, table string) error {
+	var definition string
+	err := tx.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&definition)
+	if err != nil {
+		return err
+	}
+	if m := unsupportedConstraintRegex.FindString(definition); m != "" {
+		return &SchemaError{Message: fmt.Sprintf("the %s table has %s constraints that the change would drop, rename the fields or collection instead", table, strings.ToUpper(m))}
+	}
+	return nil
+}
+
+func createTable(tx *sql.Tx, c *Collection) error {
+	_, err := tx.Exec(createTableSQL(c.Table, c))
+	return err
+}
+
+// indexRegex matches the CREATE INDEX statements accepted for collections
+var indexRegex = regexp.MustCompile("(?is)^\\s*CREATE\\s+(?:UNIQUE\\s+)?INDEX\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?[\\[`\"]?(\\w+)[\\]`\"]?\\s+ON\\s+[\\[`\"]?(\\w+)[\\]`\"]?\\s*\\(")
+
+// ParseIndex returns the index and table names of a CREATE INDEX statement
+func ParseIndex(statement string) (name, table string, ok bool) {
+	m := indexRegex.FindStringSubmatch(statement)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// RenameIndexTable points a CREATE INDEX statement at a renamed table
+func RenameIndexTable(statement, oldTable, newTable string) string {
+	m := indexRegex.FindStringSubmatchIndex(statement)
+	if m == nil || statement[m[4]:m[5]] != oldTable {
+		return statement
+	}
+	return statement[:m[4]] + newTable + statement[m[5]:]
+}
+
+func dropIndexes(tx *sql.Tx, indexes []string) error {
+	for _, index := range indexes {
+		name, _, ok := ParseIndex(index)
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS [%s]", name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createIndexes(tx *sql.Tx, indexes []string) error {
+	for _, index := range indexes {
+		if _, err := tx.Exec(index); err != nil {
+			return &SchemaError{Message: fmt.Sprintf("cannot create index %q: %v", index, err)}
+		}
+	}
+	return nil
+}
+
+// migrateFields applies field changes with ALTER TABLE where SQLite supports
+// it and falls back to rebuilding the table for type changes and for columns
+// that cannot be dropped in place (e.g. those with UNIQUE constraints).
+func migrateFields(tx *sql.Tx, old, updated *Collection) error {
+	table := updated.Table
+
+	oldFields := map[string]Field{}
+	for _, f := range old.Fields {
+		oldFields[f.ID] = f
+	}
+
+	rebuild := false
+	kept := map[string]bool{}
+	var added []Field
+	for _, f := range updated.Fields {
+		previous, ok := oldFields[f.ID]
+		if !ok {
+			added = append(added, f)
+			continue
+		}
+		kept[f.ID] = true
+
+		if previous.Type != f.Type || previous.Relation != f.Relation {
+			rebuild = true
+		}
+		if previous.ColumnName() != f.ColumnName() {
+			query := fmt.Sprintf("ALTER TABLE [%s] RENAME COLUMN [%s] TO [%s]", table, previous.ColumnName(), f.ColumnName())
+			if _, err := tx.Exec(query); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range old.Fields {
+		if kept[f.ID] || rebuild {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE [%s] DROP COLUMN [%s]", table, f.ColumnName())); err != nil {
+			rebuild = true
+		}
+	}
+
+	if rebuild {
+		return rebuildTable(tx, oldFields, updated)
+	}
+
+	for _, f := range added {
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE [%s] ADD COLUMN %s", table, columnDefinition(f))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildTable recreates the collection table from its field definitions and
+// copies the existing rows, converting values of fields whose type changed.
+// Columns of renamed fields have already been renamed in place. The NOT NULL,
+// DEFAULT, UNIQUE and foreign key constraints of the kept columns are carried
+// over; tables with other constraints are not rebuilt.
+func rebuildTable(tx *sql.Tx, oldFields map[string]Field, c *Collection) error {
+	table := c.Table
+	tmp := "_new_" + table
+
+	triggers, err := schemaSQL(tx, "trigger", table)
+	if err != nil {
+		return err
+	}
+	violationsBefore, err := foreignKeyViolations(tx, table)
+	if err != nil {
+		return err
+	}
+
+	if err := checkRebuildable(tx, table); err != nil {
+		return err
+	}
+	existing, err := tableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	uniques, err := uniqueConstraints(tx, table)
+	if err != nil {
+		return err
+	}
+
+	// Columns kept with the same type keep their definition. Those whose
+	// type changed keep NOT NULL and the ON DELETE action of their relation.
+	definitions := []string{}
+	kept := map[string]bool{}
+	for _, f := range c.Fields {
+		previous, ok := oldFields[f.ID]
+		col, exists := existing[strings.ToLower(f.ColumnName())]
+		if !ok || !exists {
+			definitions = append(definitions, columnDefinition(f))
+			continue
+		}
+		kept[strings.ToLower(f.ColumnName())] = true
+		if previous.Type == f.Type && previous.Relation == f.Relation {
+			definitions = append(definitions, col.definition())
+			continue
+		}
+
+		def := columnDefinition(f)
+		if f.Type == FieldTypeRelation && col.references != "" {
+			def = "[" + f.ColumnName() + "] TEXT " + referencesClause(f.Relation, col.onDelete)
+		}
+		if col.notNull {
+			def += " NOT NULL"
+		}
+		definitions = append(definitions, def)
+	}
+	for _, columns := range uniques {
+		quoted := make([]string, len(columns))
+		for i, column := range columns {
+			quoted[i] = "[" + column + "]"
+			if !kept[strings.ToLower(column)] {
+				quoted = nil
+				break
+			}
+		}
+		if quoted != nil {
+			definitions = append(definitions, "UNIQUE ("+strings.Join(quoted, ", ")+")")
+		}
+	}
+
+	if _, err := tx.Exec(tableSQL(tmp, c, definitions)); err != nil {
+		return err
+	}
+
+	// Rows keep their rowid, which the products search index refers to
+	columns := []string{"rowid", "[id]", "[created]", "[updated]", "[deleted]", "[collection_id]", "[collection_name]"}
+	if c.IsAuth() {
+		columns = append(columns, "["+TokenKeyColumn+"]")
+	}
+	values := append([]string(nil), columns...)
+	for _, f := range c.Fields {
+		previous, ok := oldFields[f.ID]
+		if !ok {
+			continue
+		}
+		columns = append(columns, "["+f.ColumnName()+"]")
+		values = append(values, convertColumn("["+f.ColumnName()+"]", previous.Type, f.Type))
+	}
+
+	statements := []string{
+		fmt.Sprintf("INSERT INTO [%s] (%s) SELECT %s FROM [%s]", tmp, strings.Join(columns, ", "), strings.Join(values, ", "), table),
+		fmt.Sprintf("DROP TABLE [%s]", table),
+		fmt.Sprintf("ALTER TABLE [%s] RENAME TO [%s]", tmp, table),
+	}
+	for _, statement := range append(statements, triggers...) {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	violationsAfter, err := foreignKeyViolations(tx, table)
+	if err != nil {
+		return err
+	}
+	if violationsAfter > violationsBefore {
+		return &SchemaError{Message: fmt.Sprintf("existing %s records reference missing relation records", table)}
+	}
+	return nil
+}
+
+// createTableRegex matches the start of a CREATE TABLE statement up to the
+// table name
+var createTableRegex = regexp.MustCompile("(?is)^\\s*CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?(?:\\[[^\\]]+\\]|`[^`]+`|\"[^\"]+\"|\\w+)")
+
+// tableUniqueRegex and columnUniqueRegex match the table and column UNIQUE
+// constraints of a CREATE TABLE statement
+var (
+	tableUniqueRegex  = regexp.MustCompile(`(?i),\s*(?:CONSTRAINT\s+\S+\s+)?UNIQUE\s*\([^)]*\)`)
+	columnUniqueRegex = regexp.MustCompile(`(?i)\s+UNIQUE\b`)
+)
+
+// liveUniqueIndexSQL returns the CREATE UNIQUE INDEX statement enforcing the
+// uniqueness of columns among the records that are not in the trash
+func liveUniqueIndexSQL(table string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = "[" + column + "]"
+	}
+	name := fmt.Sprintf("idx_%s_%s_unique", table, strings.Join(columns, "_"))
+	return fmt.Sprintf("CREATE UNIQUE INDEX [%s] ON [%s] (%s) WHERE [deleted] IS NULL", name, table, strings.Join(quoted, ", "))
+}
+
+// replaceUniqueConstraints rebuilds a table without its UNIQUE constraints,
+// which also apply to the records in the trash, and enforces them with
+// partial unique indexes instead. It returns the statements of the created
+// indexes.
+func replaceUniqueConstraints(tx *sql.Tx, table string) ([]string, error) {
+	uniques, err := uniqueConstraints(tx, table)
+	if err != nil || len(uniques) == 0 {
+		return nil, err
+	}
+
+	var created []string
+	for _, columns := range uniques {
+		created = append(created, liveUniqueIndexSQL(table, columns))
+	}
+	err = rewriteTable(tx, table, func(definition string) string {
+		return columnUniqueRegex.ReplaceAllString(tableUniqueRegex.ReplaceAllString(definition, ""), "")
+	}, created...)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// collectionIDDefaultRegex matches the DEFAULT clause of the collection_id
+// column in a CREATE TABLE statement
+var collectionIDDefaultRegex = regexp.MustCompile(`(?i)((?:\[collection_id\]|"collection_id"|collection_id)\s+TEXT\s+DEFAULT\s+)'[^']*'`)
+
+// useCollectionID stores the collection id in the collection_id column of
+// the existing rows of a table and makes it the column default
+func useCollectionID(tx *sql.Tx, table, id string) error {
+	if _, err := tx.Exec(fmt.Sprintf("UPDATE [%s] SET [collection_id] = ?", table), id); err != nil {
+		return err
+	}
+
+	var definition string
+	if err := tx.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&definition); err != nil {
+		return err
+	}
+	updated := collectionIDDefaultRegex.ReplaceAllString(definition, "${1}'"+id+"'")
+	if updated == definition {
+		return nil
+	}
+	return rewriteTable(tx, table, func(string) string { return updated })
+}
+
+// rewriteTable rebuilds a table from its CREATE TABLE statement as modified
+// by rewrite, copying the rows and recreating the indexes and triggers of
+// the table followed by the extra statements
+func rewriteTable(tx *sql.Tx, table string, rewrite func(definition string) string, extra ...string) error {
+	var definition string
+	if err := tx.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&definition); err != nil {
+		return err
+	}
+	indexes, err := tableIndexes(tx, table)
+	if err != nil {
+		return err
+	}
+	triggers, err := schemaSQL(tx, "trigger", table)
+	if err != nil {
+		return err
+	}
+	existing, err := tableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	for _, col := range existing {
+		columns = append(columns, "["+col.name+"]")
+	}
+	sort.Strings(columns)
+	// Rows keep their rowid, which the products search index refers to
+	columns = append([]string{"rowid"}, columns...)
+
+	tmp := "_new_" + table
+	definition = createTableRegex.ReplaceAllString(rewrite(definition), "CREATE TABLE ["+tmp+"]")
+
+	statements := []string{
+		definition,
+		fmt.Sprintf("INSERT INTO [%s] (%s) SELECT %[2]s FROM [%s]", tmp, strings.Join(columns, ", "), table),
+		fmt.Sprintf("DROP TABLE [%s]", table),
+		fmt.Sprintf("ALTER TABLE [%s] RENAME TO [%s]", tmp, table),
+	}
+	statements = append(statements, indexes...)
+	statements = append(statements, triggers...)
+	statements = append(statements, extra...)
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertColumn returns the expression copying a column into a field whose
+// type changed from one type to another
+func convertColumn(column string, from, to FieldType) string {
+	if from == to {
+		return column
+	}
+	switch to {
+	case FieldTypeNumber:
+		return "CAST(" + column + " AS NUMERIC)"
+	case FieldTypeBool:
+		return "(CASE WHEN lower(" + column + ") IN ('1', 'true') THEN TRUE ELSE FALSE END)"
+	case FieldTypeText, FieldTypeEmail:
+		return "CAST(" + column + " AS TEXT)"
+	case FieldTypeJSON:
+		return "(CASE WHEN " + column + " IS NULL OR json_valid(" + column + ") THEN " + column + " ELSE json_quote(" + column + ") END)"
+	}
+	return column
+}
+
+func foreignKeyViolations(tx *sql.Tx, table string) (int, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA foreign_key_check([%s])", table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	return count, rows.Err()
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameCollectionKeepsConstraints(t *testing.T) {
+	conn := openTestDB(t)
+
+	old, ok := FindCollection("categories")
+	require.True(t, ok)
+	updated := old.Clone()
+	updated.Name = "product_categories"
+	require.NoError(t, UpdateCollection(old, updated))
+
+	_, err := conn.Exec("INSERT INTO product_categories (id, name, slug) VALUES ('cat_1', 'Decks', 'decks')")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO product_categories (id, name, slug) VALUES ('cat_2', 'More decks', 'decks')")
+	assert.ErrorContains(t, err, "UNIQUE constraint failed: product_categories.slug")
+	_, err = conn.Exec("INSERT INTO product_categories (id, slug) VALUES ('cat_3', 'nameless')")
+	assert.ErrorContains(t, err, "NOT NULL constraint failed: product_categories.name")
+
+	var onDelete string
+	err = conn.QueryRow(`SELECT on_delete FROM pragma_foreign_key_list('subcategories') WHERE "table" = 'product_categories'`).Scan(&onDelete)
+	require.NoError(t, err)
+	assert.Equal(t, "CASCADE", onDelete)
+}
+
+func TestFieldTypeChangeKeepsConstraints(t *testing.T) {
+	conn := openTestDB(t)
+
+	old, ok := FindCollection("products")
+	require.True(t, ok)
+	updated := old.Clone()
+	for i, f := range updated.Fields {
+		if f.Name == "price" {
+			updated.Fields[i].Type = FieldTypeNumber
+		}
+	}
+	require.NoError(t, UpdateCollection(old, updated))
+
+	foreignKeys := map[string]string{}
+	rows, err := conn.Query(`SELECT "from", on_delete FROM pragma_foreign_key_list('products')`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var from, onDelete string
+		require.NoError(t, rows.Scan(&from, &onDelete))
+		foreignKeys[from] = onDelete
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, map[string]string{"category": "CASCADE", "subcategory": "SET NULL", "store": "CASCADE"}, foreignKeys)
+
+	notNull := map[string]bool{}
+	var active sql.NullString
+	rows, err = conn.Query("SELECT name, [notnull], dflt_value FROM pragma_table_info('products')")
+	require.NoError(t, err)
+	for rows.Next() {
+		var name string
+		var isNotNull bool
+		var defaultValue sql.NullString
+		require.NoError(t, rows.Scan(&name, &isNotNull, &defaultValue))
+		notNull[name] = isNotNull
+		if name == "active" {
+			active = defaultValue
+		}
+	}
+	require.NoError(t, rows.Close())
+	assert.True(t, notNull["price"])
+	assert.True(t, notNull["store"])
+	assert.False(t, notNull["description"])
+	assert.Equal(t, "TRUE", active.String)
+
+	var price float64
+	require.NoError(t, conn.QueryRow("SELECT price FROM products WHERE id = 'prod_deck_001'").Scan(&price))
+	assert.Equal(t, 59.99, price)
+}
+
+func TestFieldTypeChangeRefusesCheckConstraints(t *testing.T) {
+	openTestDB(t)
+
+	old, ok := FindCollection("stores")
+	require.True(t, ok)
+	updated := old.Clone()
+	for i, f := range updated.Fields {
+		if f.Name == "plan" {
+			updated.Fields[i].Type = FieldTypeJSON
+		}
+	}
+
+	var schemaErr *SchemaError
+	require.ErrorAs(t, UpdateCollection(old, updated), &schemaErr)
+	assert.Contains(t, schemaErr.Message, "CHECK")
+}
+
+func TestUniqueConstraintsIgnoreTrash(t *testing.T) {
+	conn := openTestDB(t)
+
+	categories, ok := FindCollection("categories")
+	require.True(t, ok)
+	assert.Contains(t, categories.Indexes, "CREATE UNIQUE INDEX [idx_categories_slug_unique] ON [categories] ([slug]) WHERE [deleted] IS NULL")
+
+	_, err := conn.Exec("UPDATE categories SET deleted = CURRENT_TIMESTAMP WHERE id = 'cat_shoes'")
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO categories (id, name, slug) VALUES ('cat_new_shoes', 'Shoes', 'shoes')")
+	require.NoError(t, err)
+	_, err = conn.Exec("UPDATE categories SET deleted = NULL WHERE id = 'cat_shoes'")
+	assert.ErrorContains(t, err, "UNIQUE constraint failed")
+
+	var subcategories int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM subcategories").Scan(&subcategories))
+	assert.Equal(t, 9, subcategories)
+}
+
+func TestRecordsStoreCollectionID(t *testing.T) {
+	conn := openTestDB(t)
+
+	old, ok := FindCollection("categories")
+	require.True(t, ok)
+	require.NotEmpty(t, old.ID)
+
+	var ids int
+	var collectionID string
+	require.NoError(t, conn.QueryRow("SELECT COUNT(DISTINCT collection_id), MAX(collection_id) FROM categories").Scan(&ids, &collectionID))
+	assert.Equal(t, 1, ids)
+	assert.Equal(t, old.ID, collectionID)
+	var defaultValue string
+	require.NoError(t, conn.QueryRow("SELECT dflt_value FROM pragma_table_info('categories') WHERE name = 'collection_id'").Scan(&defaultValue))
+	assert.Equal(t, "'"+old.ID+"'", defaultValue)
+
+	updated := old.Clone()
+	updated.Name = "product_categories"
+	require.NoError(t, UpdateCollection(old, updated))
+
+	var collectionName string
+	require.NoError(t, conn.QueryRow("SELECT collection_id, collection_name FROM product_categories WHERE id = 'cat_shoes'").Scan(&collectionID, &collectionName))
+	assert.Equal(t, old.ID, collectionID)
+	assert.Equal(t, "product_categories", collectionName)
+}
+
+func TestAuthCollectionsMigrationAddsTokenKeys(t *testing.T) {
+	conn := openTestDB(t)
+
+	var tokenKey string
+	require.NoError(t, conn.QueryRow("SELECT token_key FROM users WHERE id = 'user_sample_123'").Scan(&tokenKey))
+	assert.Len(t, tokenKey, 50)
+}
+
+func TestRebuildKeepsTokenKeys(t *testing.T) {
+	conn := openTestDB(t)
+
+	_, err := conn.Exec("INSERT INTO users (id, email, username, token_key) VALUES ('user_1', 'jane@example.com', 'jane', 'key_1')")
+	require.NoError(t, err)
+
+	old, ok := FindCollection("users")
+	require.True(t, ok)
+	updated := old.Clone()
+	for i, f := range updated.Fields {
+		if f.Name == "name" {
+			updated.Fields[i].Type = FieldTypeJSON
+		}
+	}
+	require.NoError(t, UpdateCollection(old, updated))
+
+	var tokenKey string
+	require.NoError(t, conn.QueryRow("SELECT token_key FROM users WHERE id = 'user_1'").Scan(&tokenKey))
+	assert.Equal(t, "key_1", tokenKey)
+}
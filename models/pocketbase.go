@@ -1,254 +1,122 @@
 package models
 
 import (
-	"database/sql/driver"
-	"encoding/json"
-	"errors"
+	"sort"
+	"strings"
 	"time"
 )
 
-// BaseRecord represents the common fields for all PocketBase records
-type BaseRecord struct {
-	ID           string    `json:"id" gorm:"primaryKey"`
-	Created      time.Time `json:"created"`
-	Updated      time.Time `json:"updated"`
-	CollectionID string    `json:"collectionId"`
-	CollectionName string  `json:"collectionName"`
-}
-
-// StringSlice represents a JSON array of strings
-type StringSlice []string
-
-func (s *StringSlice) Scan(value interface{}) error {
-	if value == nil {
-		*s = StringSlice{}
-		return nil
-	}
-	
-	switch v := value.(type) {
-	case []byte:
-		return json.Unmarshal(v, s)
-	case string:
-		return json.Unmarshal([]byte(v), s)
-	default:
-		return errors.New("cannot scan into StringSlice")
-	}
-}
+// Record is a single collection record as returned by the PocketBase-compatible
+// API. Keys are the field names of the collection plus the system fields
+// (id, created, updated, collectionId, collectionName).
+type Record map[string]interface{}
 
-func (s StringSlice) Value() (driver.Value, error) {
-	if len(s) == 0 {
-		return "[]", nil
-	}
-	return json.Marshal(s)
-}
-
-// JSONField represents a JSON field
-type JSONField map[string]interface{}
-
-func (j *JSONField) Scan(value interface{}) error {
-	if value == nil {
-		*j = JSONField{}
-		return nil
-	}
-	
-	switch v := value.(type) {
-	case []byte:
-		return json.Unmarshal(v, j)
-	case string:
-		return json.Unmarshal([]byte(v), j)
-	default:
-		return errors.New("cannot scan into JSONField")
-	}
+// ID returns the record id
+func (r Record) ID() string {
+	id, _ := r["id"].(string)
+	return id
 }
 
-func (j JSONField) Value() (driver.Value, error) {
-	if len(j) == 0 {
-		return "{}", nil
+// SetExpand stores an expanded relation under the record's "expand" object
+func (r Record) SetExpand(name string, value interface{}) {
+	expand, ok := r["expand"].(map[string]interface{})
+	if !ok {
+		expand = map[string]interface{}{}
+		r["expand"] = expand
 	}
-	return json.Marshal(j)
+	expand[name] = value
 }
 
-// User represents the PocketBase users collection
-type User struct {
-	BaseRecord
-	Email          string `json:"email" gorm:"uniqueIndex;not null"`
-	EmailVisibility bool  `json:"emailVisibility" gorm:"default:false"`
-	Username       string `json:"username" gorm:"uniqueIndex;not null"`
-	Name           string `json:"name"`
-	Avatar         string `json:"avatar"`
-	Verified       bool   `json:"verified" gorm:"default:false"`
-}
-
-func (User) TableName() string {
-	return "users"
-}
-
-// Category represents the categories collection
-type Category struct {
-	BaseRecord
-	Name        string `json:"name" gorm:"uniqueIndex;not null"`
-	Slug        string `json:"slug" gorm:"uniqueIndex;not null"`
-	Description string `json:"description"`
-	Image       string `json:"image"`
-}
-
-func (Category) TableName() string {
-	return "categories"
-}
-
-// Subcategory represents the subcategories collection
-type Subcategory struct {
-	BaseRecord
-	Name        string `json:"name" gorm:"not null"`
-	Slug        string `json:"slug" gorm:"not null"`
-	Description string `json:"description"`
-	Category    string `json:"category" gorm:"not null"` // relation ID
-}
-
-func (Subcategory) TableName() string {
-	return "subcategories"
-}
-
-// Store represents the stores collection
-type Store struct {
-	BaseRecord
-	Name             string `json:"name" gorm:"not null;size:50"`
-	Slug             string `json:"slug" gorm:"uniqueIndex;not null"`
-	Description      string `json:"description" gorm:"type:text"`
-	User             string `json:"user" gorm:"not null"` // relation ID
-	Plan             string `json:"plan" gorm:"default:free"`
-	PlanEndsAt       *time.Time `json:"plan_ends_at"`
-	CancelPlanAtEnd  bool   `json:"cancel_plan_at_end" gorm:"default:false"`
-	ProductLimit     int    `json:"product_limit" gorm:"default:10"`
-	TagLimit         int    `json:"tag_limit" gorm:"default:5"`
-	VariantLimit     int    `json:"variant_limit" gorm:"default:5"`
-	Active           bool   `json:"active" gorm:"default:true"`
-}
-
-func (Store) TableName() string {
-	return "stores"
-}
-
-// Product represents the products collection
-type Product struct {
-	BaseRecord
-	Name        string      `json:"name" gorm:"not null"`
-	Description string      `json:"description" gorm:"type:text"`
-	Images      StringSlice `json:"images" gorm:"type:text"`
-	Category    string      `json:"category" gorm:"not null"` // relation ID
-	Subcategory string      `json:"subcategory"`              // relation ID
-	Price       string      `json:"price" gorm:"not null"`
-	Inventory   int         `json:"inventory" gorm:"default:0"`
-	Rating      float64     `json:"rating" gorm:"default:0"`
-	Store       string      `json:"store" gorm:"not null"` // relation ID
-	Active      bool        `json:"active" gorm:"default:true"`
-}
-
-func (Product) TableName() string {
-	return "products"
-}
-
-// Cart represents the carts collection
-type Cart struct {
-	BaseRecord
-	User      string `json:"user"`       // relation ID (optional for guest carts)
-	SessionID string `json:"session_id"` // for guest carts
+// PocketBase API Response structures
+type PBListResponse struct {
+	Page       int         `json:"page"`
+	PerPage    int         `json:"perPage"`
+	TotalItems int         `json:"totalItems"`
+	TotalPages int         `json:"totalPages"`
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"nextCursor,omitempty"`
 }
 
-func (Cart) TableName() string {
-	return "carts"
+type PBAuthResponse struct {
+	Token  string      `json:"token"`
+	Record interface{} `json:"record"`
 }
 
-// CartItem represents the cart_items collection
-type CartItem struct {
-	BaseRecord
-	Cart        string `json:"cart" gorm:"not null"`        // relation ID
-	Product     string `json:"product" gorm:"not null"`     // relation ID
-	Quantity    int    `json:"quantity" gorm:"not null;min:1"`
-	Subcategory string `json:"subcategory"`                 // relation ID
+// PBAuthMethodsResponse lists the sign-in methods of an auth collection.
+// Only password authentication is supported.
+type PBAuthMethodsResponse struct {
+	Password PBPasswordAuthMethod `json:"password"`
+	OAuth2   PBOAuth2AuthMethod   `json:"oauth2"`
+	MFA      PBOTPAuthMethod      `json:"mfa"`
+	OTP      PBOTPAuthMethod      `json:"otp"`
 }
 
-func (CartItem) TableName() string {
-	return "cart_items"
+// PBPasswordAuthMethod describes password authentication and the fields
+// accepted as identity
+type PBPasswordAuthMethod struct {
+	Enabled        bool     `json:"enabled"`
+	IdentityFields []string `json:"identityFields"`
 }
 
-// Address represents the addresses collection
-type Address struct {
-	BaseRecord
-	Line1      string `json:"line1" gorm:"not null"`
-	Line2      string `json:"line2"`
-	City       string `json:"city" gorm:"not null"`
-	State      string `json:"state" gorm:"not null"`
-	PostalCode string `json:"postal_code" gorm:"not null"`
-	Country    string `json:"country" gorm:"not null"`
-	User       string `json:"user" gorm:"not null"` // relation ID
+// PBOAuth2AuthMethod describes OAuth2 authentication and its providers
+type PBOAuth2AuthMethod struct {
+	Enabled   bool          `json:"enabled"`
+	Providers []interface{} `json:"providers"`
 }
 
-func (Address) TableName() string {
-	return "addresses"
+// PBOTPAuthMethod describes one-time password and multi-factor
+// authentication
+type PBOTPAuthMethod struct {
+	Enabled  bool `json:"enabled"`
+	Duration int  `json:"duration"`
 }
 
-// Order represents the orders collection
-type Order struct {
-	BaseRecord
-	User     string    `json:"user"`           // relation ID (optional for guest orders)
-	Store    string    `json:"store" gorm:"not null"` // relation ID
-	Items    JSONField `json:"items" gorm:"type:text;not null"`
-	Quantity int       `json:"quantity"`
-	Amount   string    `json:"amount" gorm:"not null"`
-	Status   string    `json:"status" gorm:"default:pending"`
-	Name     string    `json:"name" gorm:"not null"`
-	Email    string    `json:"email" gorm:"not null"`
-	Address  string    `json:"address" gorm:"not null"` // relation ID
-	Notes    string    `json:"notes"`
+// PBBackupFileInfo describes a backup archive
+type PBBackupFileInfo struct {
+	Key      string    `json:"key"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
 }
 
-func (Order) TableName() string {
-	return "orders"
+// PBErrorResponse is the PocketBase error envelope. For validation failures
+// Data maps field names to PBFieldError values.
+type PBErrorResponse struct {
+	Code    int                    `json:"code"`
+	Message string                 `json:"message"`
+	Data    map[string]interface{} `json:"data"`
 }
 
-// Customer represents the customers collection
-type Customer struct {
-	BaseRecord
-	Name        string `json:"name"`
-	Email       string `json:"email" gorm:"not null"`
-	Store       string `json:"store" gorm:"not null"` // relation ID
-	TotalOrders int    `json:"total_orders" gorm:"default:0"`
-	TotalSpent  string `json:"total_spent" gorm:"default:0"`
+// PBFieldError describes why a single field failed validation
+type PBFieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
-func (Customer) TableName() string {
-	return "customers"
-}
+// FieldErrors maps field names to validation errors. Returned from record
+// validation or a before hook, they are rendered as the data of a 400
+// response.
+type FieldErrors map[string]PBFieldError
 
-// Notification represents the notifications collection
-type Notification struct {
-	BaseRecord
-	Email         string `json:"email" gorm:"uniqueIndex;not null"`
-	Token         string `json:"token" gorm:"uniqueIndex;not null"`
-	User          string `json:"user"` // relation ID
-	Communication bool   `json:"communication" gorm:"default:false"`
-	Newsletter    bool   `json:"newsletter" gorm:"default:false"`
-	Marketing     bool   `json:"marketing" gorm:"default:false"`
+// NewFieldError returns FieldErrors holding a single field error
+func NewFieldError(field, code, message string) FieldErrors {
+	return FieldErrors{field: {Code: code, Message: message}}
 }
 
-func (Notification) TableName() string {
-	return "notifications"
+// Add records the error of a field
+func (e FieldErrors) Add(field, code, message string) {
+	e[field] = PBFieldError{Code: code, Message: message}
 }
 
-// PocketBase API Response structures
-type PBListResponse struct {
-	Page         int         `json:"page"`
-	PerPage      int         `json:"perPage"`
-	TotalItems   int         `json:"totalItems"`
-	TotalPages   int         `json:"totalPages"`
-	Items        interface{} `json:"items"`
+func (e FieldErrors) Error() string {
+	parts := make([]string, 0, len(e))
+	for field, fe := range e {
+		parts = append(parts, field+": "+fe.Message)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
 }
 
-type PBAuthResponse struct {
-	Token  string      `json:"token"`
-	Record interface{} `json:"record"`
+// NewPBError returns an error response without field data
+func NewPBError(status int, message string) PBErrorResponse {
+	return PBErrorResponse{Code: status, Message: message, Data: map[string]interface{}{}}
 }
-
-// Expand structures for relations
-type ExpandData map[string]interface{}
\ No newline at end of file
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// Lifetimes of record auth tokens. Superuser tokens are shorter-lived.
+const (
+	RecordAuthTokenDuration    = 7 * 24 * time.Hour
+	SuperuserAuthTokenDuration = 24 * time.Hour
+)
+
+// TokenTypeAuth is the type claim of record auth tokens
+const TokenTypeAuth = "auth"
+
+// TokenKeyColumn is the column of auth collection tables holding the token
+// key of each record. Record auth tokens are signed with ACCESS_SECRET and the
+// token key, which is renewed to invalidate the issued tokens of a record.
+const TokenKeyColumn = "token_key"
+
+// NewTokenKey returns a random token key
+func NewTokenKey() string {
+	key := make([]byte, 25)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(key)
+}
+
+// RecordAuthClaims are the claims of a PocketBase-style record auth token,
+// signed with ACCESS_SECRET and the token key of the record
+type RecordAuthClaims struct {
+	ID           string `json:"id"`
+	CollectionID string `json:"collectionId"`
+	Type         string `json:"type"`
+	jwt.RegisteredClaims
+}
+
+// errNoAccessSecret is returned when ACCESS_SECRET is not configured, rather
+// than signing tokens with an empty key
+var errNoAccessSecret = errors.New("ACCESS_SECRET is not set")
+
+func accessSecret() ([]byte, error) {
+	secret := os.Getenv("ACCESS_SECRET")
+	if secret == "" {
+		return nil, errNoAccessSecret
+	}
+	return []byte(secret), nil
+}
+
+// errNotRecordAuthToken is returned for valid tokens that are not record auth
+// tokens
+var errNotRecordAuthToken = errors.New("not a record auth token")
+
+// recordAuthSecret returns the signing key of the tokens of a record
+func recordAuthSecret(tokenKey string) ([]byte, error) {
+	secret, err := accessSecret()
+	if err != nil {
+		return nil, err
+	}
+	return append(secret, tokenKey...), nil
+}
+
+// NewRecordAuthToken signs an auth token for a record of an auth collection
+// with its token key
+func NewRecordAuthToken(collection *Collection, recordID, tokenKey string) (string, error) {
+	duration := RecordAuthTokenDuration
+	if collection.Name == SuperusersCollectionName {
+		duration = SuperuserAuthTokenDuration
+	}
+	claims := RecordAuthClaims{
+		ID:           recordID,
+		CollectionID: collection.ID,
+		Type:         TokenTypeAuth,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+		},
+	}
+	secret, err := recordAuthSecret(tokenKey)
+	if err != nil {
+		return "", err
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseRecordAuthToken verifies a record auth token against the token key
+// returned by tokenKey for the record of its claims, and returns the claims
+func ParseRecordAuthToken(token string, tokenKey func(claims *RecordAuthClaims) (string, error)) (*RecordAuthClaims, error) {
+	claims := &RecordAuthClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		if claims.Type != TokenTypeAuth || claims.ID == "" || claims.ExpiresAt == nil {
+			return nil, errNotRecordAuthToken
+		}
+		key, err := tokenKey(claims)
+		if err != nil {
+			return nil, err
+		}
+		return recordAuthSecret(key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,219 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Full-text search of products.
+//
+// Products are indexed in a SQLite full-text table holding their name,
+// description and the names of their category and store. Triggers on the
+// products, categories and stores tables keep it in sync, including with
+// writes that do not go through the API. The index uses FTS5, so the SQLite
+// driver must be built with it (go build -tags sqlite_fts5); other builds
+// refuse to start. Index rows share the rowid of their product.
+//
+// It uses the unicode61 tokenizer, which lowercases text and removes
+// diacritics so that "giay" matches "giày". It does not fold "đ", which has
+// no decomposition, so indexed text has it replaced with "ď", which folds to
+// "d" and has the same UTF-8 length. SearchText restores it in snippets.
+
+// ProductsSearchCollection is the collection indexed for full-text search
+const ProductsSearchCollection = "products"
+
+// ProductsSearchColumns are the indexed columns of the search table, after
+// the unindexed product id, in the order used by bm25 weights and snippets
+var ProductsSearchColumns = []string{"name", "description", "category", "store"}
+
+// productsSearchTriggers are the triggers keeping the search table in sync
+var productsSearchTriggers = []string{
+	"_products_search_insert", "_products_search_update", "_products_search_delete",
+	"_products_search_category", "_products_search_store",
+}
+
+// ProductsSearchTable is the FTS5 table of the search index
+const ProductsSearchTable = "_products_fts5"
+
+// errNoFTS5 is returned at startup by builds whose SQLite lacks FTS5
+var errNoFTS5 = errors.New("SQLite FTS5 is not available, build with -tags sqlite_fts5")
+
+// checkSearchModule fails when the SQLite driver was built without FTS5
+func checkSearchModule(conn *sql.DB) error {
+	var fts5 bool
+	if err := conn.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		return err
+	}
+	if !fts5 {
+		return errNoFTS5
+	}
+	return nil
+}
+
+// SearchText restores the text of an indexed column, as returned by snippets
+func SearchText(text string) string {
+	return strings.NewReplacer("ď", "đ", "Ď", "Đ").Replace(text)
+}
+
+// SearchTerm folds a search term the way indexed text is folded
+func SearchTerm(term string) string {
+	return strings.NewReplacer("đ", "ď", "Đ", "Ď").Replace(term)
+}
+
+// searchFold returns the SQL expression folding a text value for the index
+func searchFold(expr string) string {
+	return fmt.Sprintf("replace(replace(coalesce(%s, ''), 'đ', 'ď'), 'Đ', 'Ď')", expr)
+}
+
+// productsSearchSchema returns the collections the search index reads from,
+// or ok false when their schema no longer supports it
+func productsSearchSchema() (products, categories, stores *Collection, ok bool) {
+	products, ok = FindCollection(ProductsSearchCollection)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	for _, name := range []string{"name", "description"} {
+		if f, found := products.Field(name); !found || f.Type != FieldTypeText {
+			return nil, nil, nil, false
+		}
+	}
+	targets := map[string]*Collection{}
+	for _, name := range []string{"category", "store"} {
+		f, found := products.Field(name)
+		if !found || f.Type != FieldTypeRelation {
+			return nil, nil, nil, false
+		}
+		target, found := FindCollection(f.Relation)
+		if !found {
+			return nil, nil, nil, false
+		}
+		if nameField, found := target.Field("name"); !found || nameField.Type != FieldTypeText {
+			return nil, nil, nil, false
+		}
+		targets[name] = target
+	}
+	return products, targets["category"], targets["store"], true
+}
+
+// ProductsSearchEnabled reports whether products are indexed for search
+func ProductsSearchEnabled() bool {
+	_, _, _, ok := productsSearchSchema()
+	return ok
+}
+
+// dropProductsSearchTriggers removes the triggers of the search index, which
+// must not get in the way of schema changes
+func dropProductsSearchTriggers(tx *sql.Tx) error {
+	for _, trigger := range productsSearchTriggers {
+		if _, err := tx.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS [%s]", trigger)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefreshProductsSearch creates the search index and its triggers for the
+// current schema of the indexed collections, rebuilding the index when it
+// was not kept in sync. The index is dropped when the collections no longer
+// have the indexed fields.
+func RefreshProductsSearch() error {
+	return migrate(func(tx *sql.Tx) error {
+		var triggers int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE '\_products\_search\_%' ESCAPE '\'`).Scan(&triggers)
+		if err != nil {
+			return err
+		}
+		synced := triggers == len(productsSearchTriggers)
+		if err := dropProductsSearchTriggers(tx); err != nil {
+			return err
+		}
+
+		table := ProductsSearchTable
+
+		products, categories, stores, ok := productsSearchSchema()
+		if !ok {
+			_, err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS [%s]", table))
+			return err
+		}
+
+		exists, err := tableExists(tx, table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if _, err := tx.Exec(productsSearchTableSQL(table)); err != nil {
+				return err
+			}
+			synced = false
+		}
+
+		category, _ := products.Field("category")
+		store, _ := products.Field("store")
+		name, _ := products.Field("name")
+		description, _ := products.Field("description")
+		categoryName, _ := categories.Field("name")
+		storeName, _ := stores.Field("name")
+
+		// indexRow selects the index values of the product row p
+		indexRow := func(p string) string {
+			return fmt.Sprintf("%[1]s.rowid, %[1]s.[id], %[2]s, %[3]s, %[4]s, %[5]s", p,
+				searchFold(p+".["+name.ColumnName()+"]"),
+				searchFold(p+".["+description.ColumnName()+"]"),
+				searchFold(fmt.Sprintf("(SELECT [%s] FROM [%s] WHERE [id] = %s.[%s])", categoryName.ColumnName(), categories.Table, p, category.ColumnName())),
+				searchFold(fmt.Sprintf("(SELECT [%s] FROM [%s] WHERE [id] = %s.[%s])", storeName.ColumnName(), stores.Table, p, store.ColumnName())))
+		}
+		columns := "rowid, product, " + strings.Join(ProductsSearchColumns, ", ")
+
+		statements := []string{
+			fmt.Sprintf(`CREATE TRIGGER [_products_search_insert] AFTER INSERT ON [%s] BEGIN
+    INSERT INTO [%s] (%s) SELECT %s;
+END`, products.Table, table, columns, indexRow("new")),
+			fmt.Sprintf(`CREATE TRIGGER [_products_search_update] AFTER UPDATE ON [%s] BEGIN
+    DELETE FROM [%[2]s] WHERE rowid = old.rowid;
+    INSERT INTO [%[2]s] (%[3]s) SELECT %[4]s;
+END`, products.Table, table, columns, indexRow("new")),
+			fmt.Sprintf(`CREATE TRIGGER [_products_search_delete] AFTER DELETE ON [%s] BEGIN
+    DELETE FROM [%s] WHERE rowid = old.rowid;
+END`, products.Table, table),
+			fmt.Sprintf(`CREATE TRIGGER [_products_search_category] AFTER UPDATE OF [%s] ON [%s] BEGIN
+    UPDATE [%s] SET category = %s WHERE rowid IN (SELECT rowid FROM [%s] WHERE [%s] = new.[id]);
+END`, categoryName.ColumnName(), categories.Table, table, searchFold("new.["+categoryName.ColumnName()+"]"), products.Table, category.ColumnName()),
+			fmt.Sprintf(`CREATE TRIGGER [_products_search_store] AFTER UPDATE OF [%s] ON [%s] BEGIN
+    UPDATE [%s] SET store = %s WHERE rowid IN (SELECT rowid FROM [%s] WHERE [%s] = new.[id]);
+END`, storeName.ColumnName(), stores.Table, table, searchFold("new.["+storeName.ColumnName()+"]"), products.Table, store.ColumnName()),
+		}
+		if !synced {
+			statements = append(statements,
+				fmt.Sprintf("DELETE FROM [%s]", table),
+				fmt.Sprintf("INSERT INTO [%s] (%s) SELECT %s FROM [%s] p", table, columns, indexRow("p"), products.Table))
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		if !synced {
+			log.Println("Rebuilt the products search index")
+		}
+		return nil
+	})
+}
+
+// refreshProductsSearch refreshes the search index after a schema change,
+// which is kept even if the index cannot follow it
+func refreshProductsSearch() error {
+	if err := RefreshProductsSearch(); err != nil {
+		log.Printf("Failed to refresh the products search index: %v", err)
+	}
+	return nil
+}
+
+func productsSearchTableSQL(table string) string {
+	return fmt.Sprintf(`CREATE VIRTUAL TABLE [%s] USING fts5(
+    product UNINDEXED, %s,
+    tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3'
+)`, table, strings.Join(ProductsSearchColumns, ", "))
+}
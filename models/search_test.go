@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// searchProducts returns the ids of the products matching term, joined on
+// the rowid shared by the index rows and their product
+func searchProducts(t *testing.T, conn *sql.DB, term string) []string {
+	t.Helper()
+
+	rows, err := conn.Query("SELECT p.id FROM [_products_fts5] JOIN products p ON p.rowid = [_products_fts5].rowid WHERE [_products_fts5] MATCH ? AND [_products_fts5].product = p.id ORDER BY p.id", term)
+	require.NoError(t, err)
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	return ids
+}
+
+func TestProductsSchemaChangeKeepsSearchResults(t *testing.T) {
+	conn := openTestDB(t)
+	if err := checkSearchModule(conn); err != nil {
+		t.Skip(err)
+	}
+	require.NoError(t, RefreshProductsSearch())
+
+	// Purged products leave gaps in the rowids, which the index rows share
+	_, err := conn.Exec("DELETE FROM products WHERE id = 'prod_wheels_001'")
+	require.NoError(t, err)
+	before := searchProducts(t, conn, "vieshare")
+	require.Contains(t, before, "prod_tshirt_001")
+
+	old, ok := FindCollection("products")
+	require.True(t, ok)
+	updated := old.Clone()
+	for i, f := range updated.Fields {
+		if f.Name == "price" {
+			updated.Fields[i].Type = FieldTypeNumber
+		}
+	}
+	require.NoError(t, UpdateCollection(old, updated))
+	assert.Equal(t, before, searchProducts(t, conn, "vieshare"))
+
+	_, err = conn.Exec("UPDATE products SET name = 'Logo Hoodie' WHERE id = 'prod_tshirt_001'")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod_tshirt_001"}, searchProducts(t, conn, "hoodie"))
+	assert.Empty(t, searchProducts(t, conn, "name:shirt"))
+}
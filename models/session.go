@@ -0,0 +1,228 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/VieShare/vieshare-gin/db"
+	uuid "github.com/google/uuid"
+)
+
+// Sessions of the v1 auth API.
+//
+// Each login opens a session, which is the family of the refresh tokens
+// issued by the login and by every later refresh. Only the latest refresh
+// token of a session is valid: presenting an older one means it was
+// replayed, e.g. after being stolen, and revokes the whole session.
+// Revoking a session deletes its tokens from the token store, so its access
+// token stops working at once. The sessions of a user moved to the trash are
+// revoked.
+
+const sessionsTableSQL = `
+CREATE TABLE IF NOT EXISTS _sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    device TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created DATETIME NOT NULL,
+    last_used DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    revoked DATETIME DEFAULT NULL,
+    access_uuid TEXT NOT NULL DEFAULT '',
+    refresh_uuid TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON _sessions (user_id, last_used);`
+
+// sessionTouchInterval is how often the last use of a session is updated
+// by the requests made with its access tokens
+const sessionTouchInterval = time.Minute
+
+// sessionRetention is how long expired and revoked sessions are kept
+const sessionRetention = 30 * 24 * time.Hour
+
+var (
+	// ErrSessionNotFound is returned for sessions that do not exist, have
+	// expired or were revoked
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrRefreshTokenReused is returned when a rotated refresh token is
+	// presented again; the session has been revoked
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// Session is a login of a user on a device
+type Session struct {
+	ID          string    `db:"id" json:"id"`
+	UserID      string    `db:"user_id" json:"-"`
+	Device      string    `db:"device" json:"device"`
+	IP          string    `db:"ip" json:"ip"`
+	UserAgent   string    `db:"user_agent" json:"user_agent"`
+	Created     time.Time `db:"created" json:"created"`
+	LastUsed    time.Time `db:"last_used" json:"last_used"`
+	Expires     time.Time `db:"expires" json:"expires"`
+	AccessUUID  string    `db:"access_uuid" json:"-"`
+	RefreshUUID string    `db:"refresh_uuid" json:"-"`
+	// Current marks the session of the request listing the sessions
+	Current bool `db:"-" json:"current"`
+}
+
+// SessionClient describes the client a session is used from
+type SessionClient struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+// SessionsResponse lists the active sessions of a user
+type SessionsResponse struct {
+	Sessions []Session `json:"sessions"`
+}
+
+// sessionColumns selects the _sessions columns of a Session
+const sessionColumns = "id, user_id, device, ip, user_agent, created, last_used, expires, access_uuid, refresh_uuid"
+
+// SessionModel ...
+type SessionModel struct{}
+
+// createSessions creates the table of the v1 sessions
+func createSessions(tx *sql.Tx) error {
+	_, err := tx.Exec(sessionsTableSQL)
+	return err
+}
+
+// Open starts a session for the user and issues its first tokens
+func (m SessionModel) Open(userID string, client SessionClient) (*TokenDetails, error) {
+	td, err := authModel.CreateToken(userID, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	conn := db.GetDB().Db
+	if _, err := conn.Exec("DELETE FROM _sessions WHERE user_id = ? AND (expires < ? OR revoked < ?)",
+		userID, now.Add(-sessionRetention), now.Add(-sessionRetention)); err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(`INSERT INTO _sessions (id, user_id, device, ip, user_agent, created, last_used, expires, access_uuid, refresh_uuid)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		td.SessionID, userID, client.Device, client.IP, client.UserAgent, now, now,
+		time.Unix(td.RtExpires, 0).UTC(), td.AccessUUID, td.RefreshUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authModel.CreateAuth(userID, td); err != nil {
+		return nil, err
+	}
+	return td, nil
+}
+
+// Refresh rotates the tokens of a session in exchange for its latest
+// refresh token. Any other refresh token of the session revokes it.
+func (m SessionModel) Refresh(userID, sessionID, refreshUUID string, client SessionClient) (*TokenDetails, error) {
+	session, err := m.active(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.RefreshUUID != refreshUUID {
+		return nil, m.reused(session)
+	}
+
+	td, err := authModel.CreateToken(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the first of concurrent refreshes with the same token wins
+	result, err := db.GetDB().Db.Exec(`UPDATE _sessions SET access_uuid = ?, refresh_uuid = ?, last_used = ?, expires = ?, ip = ?, user_agent = COALESCE(NULLIF(?, ''), user_agent)
+		WHERE id = ? AND refresh_uuid = ? AND revoked IS NULL`,
+		td.AccessUUID, td.RefreshUUID, time.Now().UTC(), time.Unix(td.RtExpires, 0).UTC(), client.IP, client.UserAgent,
+		sessionID, refreshUUID)
+	if err != nil {
+		return nil, err
+	}
+	if rotated, _ := result.RowsAffected(); rotated == 0 {
+		return nil, m.reused(session)
+	}
+
+	if _, err := authModel.DeleteAuth(session.AccessUUID, session.RefreshUUID); err != nil {
+		return nil, err
+	}
+	if err := authModel.CreateAuth(userID, td); err != nil {
+		return nil, err
+	}
+	return td, nil
+}
+
+// List returns the active sessions of the user, most recently used first
+func (m SessionModel) List(userID string) (sessions []Session, err error) {
+	_, err = db.GetDB().Select(&sessions, "SELECT "+sessionColumns+" FROM _sessions WHERE user_id = ? AND revoked IS NULL AND expires > ? ORDER BY last_used DESC",
+		userID, time.Now().UTC())
+	if sessions == nil {
+		sessions = []Session{}
+	}
+	return sessions, err
+}
+
+// Revoke ends a session of the user
+func (m SessionModel) Revoke(userID, sessionID string) error {
+	session, err := m.active(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	return m.revoke(session)
+}
+
+// RevokeAll ends every session of the user and returns how many were active
+func (m SessionModel) RevokeAll(userID string) (int, error) {
+	sessions, err := m.List(userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, session := range sessions {
+		if err := m.revoke(session); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// Touch records that an access token of the session was used. The update
+// is skipped while the last recorded use is recent.
+func (m SessionModel) Touch(sessionID string) error {
+	now := time.Now().UTC()
+	_, err := db.GetDB().Db.Exec("UPDATE _sessions SET last_used = ? WHERE id = ? AND last_used < ?",
+		now, sessionID, now.Add(-sessionTouchInterval))
+	return err
+}
+
+// active loads a session of the user that is neither expired nor revoked,
+// as long as the user exists and is not in the trash
+func (m SessionModel) active(userID, sessionID string) (session Session, err error) {
+	err = db.GetDB().SelectOne(&session, "SELECT "+sessionColumns+` FROM _sessions WHERE id = ? AND user_id = ? AND revoked IS NULL AND expires > ?
+		AND EXISTS (SELECT 1 FROM users WHERE users.id = _sessions.user_id AND users.deleted IS NULL)`,
+		sessionID, userID, time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, ErrSessionNotFound
+	}
+	return session, err
+}
+
+// revoke marks the session revoked and deletes its tokens
+func (m SessionModel) revoke(session Session) error {
+	if _, err := db.GetDB().Db.Exec("UPDATE _sessions SET revoked = ? WHERE id = ? AND revoked IS NULL", time.Now().UTC(), session.ID); err != nil {
+		return err
+	}
+	_, err := authModel.DeleteAuth(session.AccessUUID, session.RefreshUUID)
+	return err
+}
+
+// reused revokes a session whose rotated refresh token was presented
+func (m SessionModel) reused(session Session) error {
+	if err := m.revoke(session); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Application settings.
+//
+// Superusers edit the settings through /api/settings. They are stored as a
+// JSON object in the _params table and cached in memory; values that were
+// never saved keep their defaults, some of which come from the environment.
+
+const paramsTableSQL = `
+CREATE TABLE IF NOT EXISTS _params (
+    id TEXT PRIMARY KEY,
+    value JSON NOT NULL DEFAULT '{}',
+    created DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+// settingsParam is the _params row holding the settings
+const settingsParam = "settings"
+
+const (
+	defaultLogsMaxDays        = 7
+	defaultTrashRetentionDays = 30
+	defaultBatchMaxRequests   = 50
+	defaultAppName            = "VieShare"
+	defaultAppURL             = "http://localhost:9000"
+)
+
+// Settings are the application settings editable by superusers
+type Settings struct {
+	Meta  MetaSettings  `json:"meta"`
+	Logs  LogsSettings  `json:"logs"`
+	Trash TrashSettings `json:"trash"`
+	Batch BatchSettings `json:"batch"`
+}
+
+// MetaSettings describe the application
+type MetaSettings struct {
+	AppName string `json:"appName"`
+	AppURL  string `json:"appURL"`
+}
+
+// LogsSettings control the request logs
+type LogsSettings struct {
+	// MaxDays is how long request logs are kept, zero disables them
+	MaxDays int `json:"maxDays"`
+}
+
+// TrashSettings control the trash of deleted records
+type TrashSettings struct {
+	// RetentionDays is how long deleted records stay in the trash, zero
+	// keeps them until they are purged on request
+	RetentionDays int `json:"retentionDays"`
+}
+
+// BatchSettings control the /api/batch endpoint
+type BatchSettings struct {
+	Enabled     bool `json:"enabled"`
+	MaxRequests int  `json:"maxRequests"`
+}
+
+var (
+	settingsMu sync.RWMutex
+	settings   *Settings
+)
+
+// DefaultSettings returns the settings used until they are saved. The trash
+// retention defaults to TRASH_RETENTION_DAYS.
+func DefaultSettings() Settings {
+	return Settings{
+		Meta:  MetaSettings{AppName: defaultAppName, AppURL: defaultAppURL},
+		Logs:  LogsSettings{MaxDays: defaultLogsMaxDays},
+		Trash: TrashSettings{RetentionDays: envDays("TRASH_RETENTION_DAYS", defaultTrashRetentionDays)},
+		Batch: BatchSettings{Enabled: true, MaxRequests: defaultBatchMaxRequests},
+	}
+}
+
+// envDays reads a non-negative number of days from the environment
+func envDays(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 0 {
+		log.Printf("Invalid %s %q, using %d", name, raw, fallback)
+		return fallback
+	}
+	return days
+}
+
+// GetSettings returns a copy of the current settings
+func GetSettings() Settings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	if settings == nil {
+		return DefaultSettings()
+	}
+	return *settings
+}
+
+// SaveSettings stores s and makes it the current settings
+func SaveSettings(conn *sql.DB, s Settings) error {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	_, err = conn.Exec(`INSERT INTO _params (id, value) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET value = excluded.value, updated = ?`,
+		settingsParam, string(encoded), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	settings = &s
+	return nil
+}
+
+// createParams creates the table of the application settings
+func createParams(tx *sql.Tx) error {
+	_, err := tx.Exec(paramsTableSQL)
+	return err
+}
+
+// loadSettings reads the stored settings over the defaults
+func loadSettings(conn *sql.DB) error {
+	s := DefaultSettings()
+
+	var value string
+	err := conn.QueryRow("SELECT value FROM _params WHERE id = ?", settingsParam).Scan(&value)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(value), &s); err != nil {
+			return errors.New("invalid stored settings: " + err.Error())
+		}
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings = &s
+	return nil
+}
@@ -19,30 +19,37 @@ type MessageResponse struct {
 	Message string `json:"message"`
 }
 
-// LegacyUser for backward compatibility with existing auth system
+// LegacyUser is the v1 representation of a record of the users collection
 type LegacyUser struct {
-	ID        int64  `db:"id, primarykey, autoincrement" json:"id"`
-	Email     string `db:"email" json:"email"`
-	Password  string `db:"password" json:"-"`
-	Name      string `db:"name" json:"name"`
-	UpdatedAt int64  `db:"updated_at" json:"-"`
-	CreatedAt int64  `db:"created_at" json:"-"`
+	ID       string `db:"id" json:"id"`
+	Email    string `db:"email" json:"email"`
+	Password string `db:"password" json:"-"`
+	Name     string `db:"name" json:"name"`
 }
 
+// legacyUserColumns selects the users columns of a LegacyUser
+const legacyUserColumns = "id, email, COALESCE(password, '') AS password, COALESCE(name, '') AS name"
+
 // UserModel ...
 type UserModel struct{}
 
 var authModel = new(AuthModel)
+var sessionModel = new(SessionModel)
 
-// Login ...
-func (m UserModel) Login(form forms.LoginForm) (user LegacyUser, token Token, err error) {
+// Login checks the credentials and opens a session for the client
+func (m UserModel) Login(form forms.LoginForm, client SessionClient) (user LegacyUser, token Token, err error) {
 
-	err = db.GetDB().SelectOne(&user, "SELECT id, email, password, name, updated_at, created_at FROM user WHERE email=LOWER(?) LIMIT 1", form.Email)
+	err = db.GetDB().SelectOne(&user, "SELECT "+legacyUserColumns+" FROM users WHERE email = ? COLLATE NOCASE AND deleted IS NULL LIMIT 1", form.Email)
 
 	if err != nil {
 		return user, token, err
 	}
 
+	//Users without a password cannot sign in
+	if user.Password == "" {
+		return user, token, errors.New("password not set")
+	}
+
 	//Compare the password form and database if match
 	bytePassword := []byte(form.Password)
 	byteHashedPassword := []byte(user.Password)
@@ -53,55 +60,20 @@ func (m UserModel) Login(form forms.LoginForm) (user LegacyUser, token Token, er
 		return user, token, err
 	}
 
-	//Generate the JWT auth token
-	tokenDetails, err := authModel.CreateToken(user.ID)
+	//Generate the JWT auth token of a new session. Tokens that cannot be
+	//stored would be rejected by every protected call.
+	tokenDetails, err := sessionModel.Open(user.ID, client)
 	if err != nil {
 		return user, token, err
 	}
-
-	saveErr := authModel.CreateAuth(user.ID, tokenDetails)
-	if saveErr == nil {
-		token.AccessToken = tokenDetails.AccessToken
-		token.RefreshToken = tokenDetails.RefreshToken
-	}
+	token.AccessToken = tokenDetails.AccessToken
+	token.RefreshToken = tokenDetails.RefreshToken
 
 	return user, token, nil
 }
 
-// Register ...
-func (m UserModel) Register(form forms.RegisterForm) (user LegacyUser, err error) {
-	getDb := db.GetDB()
-
-	//Check if the user exists in database
-	checkUser, err := getDb.SelectInt("SELECT count(id) FROM user WHERE email=LOWER(?) LIMIT 1", form.Email)
-	if err != nil {
-		return user, errors.New("something went wrong, please try again later")
-	}
-
-	if checkUser > 0 {
-		return user, errors.New("email already exists")
-	}
-
-	bytePassword := []byte(form.Password)
-	hashedPassword, err := bcrypt.GenerateFromPassword(bytePassword, bcrypt.DefaultCost)
-	if err != nil {
-		return user, errors.New("something went wrong, please try again later")
-	}
-
-	//Create the user and return back the user ID
-	err = getDb.QueryRow("INSERT INTO user(email, password, name) VALUES(?, ?, ?) RETURNING id", form.Email, string(hashedPassword), form.Name).Scan(&user.ID)
-	if err != nil {
-		return user, errors.New("something went wrong, please try again later")
-	}
-
-	user.Name = form.Name
-	user.Email = form.Email
-
-	return user, err
-}
-
 // One ...
-func (m UserModel) One(userID int64) (user LegacyUser, err error) {
-	err = db.GetDB().SelectOne(&user, "SELECT id, email, name FROM user WHERE id=? LIMIT 1", userID)
+func (m UserModel) One(userID string) (user LegacyUser, err error) {
+	err = db.GetDB().SelectOne(&user, "SELECT "+legacyUserColumns+" FROM users WHERE id = ? AND deleted IS NULL LIMIT 1", userID)
 	return user, err
 }
@@ -2,8 +2,11 @@ package routers
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/VieShare/vieshare-gin/controllers"
+	"github.com/VieShare/vieshare-gin/models"
 	"github.com/gin-gonic/gin"
 	uuid "github.com/google/uuid"
 )
@@ -13,9 +16,9 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost")
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "X-Requested-With, Content-Type, Origin, Authorization, Accept, Client-Security-Token, Accept-Encoding, x-access-token")
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "X-Requested-With, Content-Type, Origin, Authorization, Accept, Client-Security-Token, Accept-Encoding, x-access-token, If-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length, ETag")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -28,14 +31,26 @@ func CORSMiddleware() gin.HandlerFunc {
 }
 
 // RequestIDMiddleware generates a unique ID and attaches it to each request
+// and its audit log entries
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		uuid := uuid.New()
 		c.Writer.Header().Set("X-Request-Id", uuid.String())
+		controllers.SetRequestID(c, uuid.String())
 		c.Next()
 	}
 }
 
+// RequestLogMiddleware adds each request to the request logs once it has
+// been handled
+func RequestLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+		controllers.LogRequest(c, started)
+	}
+}
+
 // TokenAuthMiddleware validates the access_token in the header for JWT authentication
 func TokenAuthMiddleware() gin.HandlerFunc {
 	auth := new(controllers.AuthController)
@@ -43,4 +58,26 @@ func TokenAuthMiddleware() gin.HandlerFunc {
 		auth.TokenValid(c)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RecordAuthMiddleware loads the admin or record credentials of the
+// Authorization header for the API rules. Requests without valid credentials
+// continue as guests.
+func RecordAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		controllers.LoadRequestAuth(c)
+		c.Next()
+	}
+}
+
+// AdminAuthMiddleware restricts a route group to requests authenticated by
+// RecordAuthMiddleware as a superuser or with the ADMIN_TOKEN
+func AdminAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !controllers.IsAdmin(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.NewPBError(http.StatusForbidden, "Only admins can perform this action."))
+			return
+		}
+		c.Next()
+	}
+}
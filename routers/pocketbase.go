@@ -8,17 +8,76 @@ import (
 // SetupPocketBaseRoutes sets up PocketBase-compatible API routes
 func SetupPocketBaseRoutes(r *gin.RouterGroup) {
 	pb := new(controllers.PocketBaseController)
-	
+
+	// Log every request once it has been answered
+	r.Use(RequestLogMiddleware())
+
+	// Admin and record credentials for the API rules
+	r.Use(RecordAuthMiddleware())
+
 	// Health check
 	r.GET("/health", pb.Health)
-	
-	// Collections CRUD operations
+
+	// Transactional batch of record operations
+	r.POST("/batch", pb.Batch)
+
+	// Realtime record events over Server-Sent Events
+	realtime := new(controllers.RealtimeController)
+	r.GET("/realtime", realtime.Connect)
+	r.POST("/realtime", realtime.Subscribe)
+
+	// Files of record file fields
+	files := new(controllers.FileController)
+	r.GET("/files/:collection/:recordId/:filename", files.Download)
+
+	// Full-text product search
+	search := new(controllers.SearchController)
+	r.GET("/search/products", search.Products)
+
+	// Collection schema management and trash (admin only)
+	schema := new(controllers.CollectionController)
+	trash := new(controllers.TrashController)
+	admin := r.Group("/collections", AdminAuthMiddleware())
+	{
+		admin.GET("", schema.List)
+		admin.POST("", schema.Create)
+		admin.GET("/:collection", schema.View)
+		admin.PATCH("/:collection", schema.Update)
+		admin.DELETE("/:collection", schema.Delete)
+		admin.GET("/:collection/trash", trash.List)
+		admin.POST("/:collection/trash/:id/restore", trash.Restore)
+		admin.DELETE("/:collection/trash/:id", trash.Purge)
+	}
+
+	// Request logs, settings and backups (admin only)
+	logs := new(controllers.LogController)
+	settings := new(controllers.SettingsController)
+	backups := new(controllers.BackupController)
+	management := r.Group("", AdminAuthMiddleware())
+	{
+		management.GET("/logs", logs.List)
+		management.GET("/logs/:id", logs.View)
+		management.GET("/settings", settings.View)
+		management.PATCH("/settings", settings.Update)
+		management.GET("/backups", backups.List)
+		management.POST("/backups", backups.Create)
+		management.GET("/backups/:key", backups.Download)
+		management.DELETE("/backups/:key", backups.Delete)
+	}
+
+	// Collections CRUD operations and sign-in of auth collection records
+	audit := new(controllers.AuditController)
+	auth := new(controllers.RecordAuthController)
 	collections := r.Group("/collections/:collection")
 	{
+		collections.GET("/auth-methods", auth.AuthMethods)
+		collections.POST("/auth-with-password", auth.AuthWithPassword)
+		collections.POST("/auth-refresh", auth.AuthRefresh)
+		collections.GET("/records/:id/audit", AdminAuthMiddleware(), audit.Record)
 		collections.GET("/records", pb.ListRecords)
 		collections.GET("/records/:id", pb.GetRecord)
 		collections.POST("/records", pb.CreateRecord)
 		collections.PATCH("/records/:id", pb.UpdateRecord)
 		collections.DELETE("/records/:id", pb.DeleteRecord)
 	}
-}
\ No newline at end of file
+}
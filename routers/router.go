@@ -8,7 +8,7 @@ import (
 // SetupV1Routes sets up all v1 API routes
 func SetupV1Routes(r *gin.RouterGroup, auth gin.HandlerFunc) {
 	// User routes
-	setupUserRoutes(r)
+	setupUserRoutes(r, auth)
 	
 	// Auth routes  
 	setupAuthRoutes(r)
@@ -17,13 +17,18 @@ func SetupV1Routes(r *gin.RouterGroup, auth gin.HandlerFunc) {
 	setupArticleRoutes(r, auth)
 }
 
-// setupUserRoutes sets up user-related routes
-func setupUserRoutes(r *gin.RouterGroup) {
+// setupUserRoutes sets up user-related routes, the sessions ones with auth middleware
+func setupUserRoutes(r *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	user := new(controllers.UserController)
+	session := new(controllers.SessionController)
 	
 	r.POST("/user/login", user.Login)
 	r.POST("/user/register", user.Register)
 	r.GET("/user/logout", user.Logout)
+	
+	r.GET("/user/sessions", authMiddleware, session.List)
+	r.DELETE("/user/sessions", authMiddleware, session.RevokeAll)
+	r.DELETE("/user/sessions/:id", authMiddleware, session.Revoke)
 }
 
 // setupAuthRoutes sets up authentication routes